@@ -37,17 +37,24 @@ func main() {
 		panic(err)
 	}
 
-	go application.MustRun(ctxWithCancel)
+	// Drained agent stops by itself.
+	drained := make(chan struct{})
+	go func() {
+		application.MustRun(ctxWithCancel)
+		close(drained)
+	}()
 
 	// Graceful shotdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
-	sign := <-stop
+	select {
+	case sign := <-stop:
+		log.Info("stopping agent", slog.String("signal", sign.String()))
 
-	log.Info("stopping agent", slog.String("signal", sign.String()))
-
-	application.Stop(ctxWithCancel)
+		application.Stop(ctxWithCancel)
+	case <-drained:
+	}
 
 	log.Info("agent stopped")
 }
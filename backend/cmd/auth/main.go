@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	grpcapp "github.com/Prrromanssss/DAEC-fullstack/internal/app/grpc"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/config"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/setup"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/services/auth"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 )
 
+// cleanupInterval is how often expired refresh tokens and revoked access tokens are deleted.
+const cleanupInterval = time.Hour
+
 func main() {
+	ctxWithCancel, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Load Config
 	cfg := config.MustLoad()
 
@@ -29,12 +39,30 @@ func main() {
 	// Configuration Storage
 	dbCfg := storage.NewStorage(log, cfg.StorageURL)
 
-	authService := auth.New(log, dbCfg, dbCfg, cfg.TokenTTL)
+	// Configuration Keys
+	keys := mustLoadKeys(log, cfg.JWTKeys)
+
+	authService := auth.New(
+		log,
+		dbCfg,
+		dbCfg,
+		dbCfg,
+		cfg.TokenTTL,
+		cfg.RefreshTokenTTL,
+		keys,
+		cfg.AdminEmails,
+	)
 
 	grpcApp := grpcapp.New(log, authService, cfg.GRPCServer.Address)
 
 	go grpcApp.MustRun()
 
+	go deleteExpiredTokens(ctxWithCancel, log, authService)
+
+	if cfg.JWTKeys.Dir != "" {
+		go reloadKeys(ctxWithCancel, log, authService, cfg.JWTKeys.ReloadInterval)
+	}
+
 	// Graceful shotdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -47,3 +75,67 @@ func main() {
 
 	log.Info("grpc server stopped")
 }
+
+func deleteExpiredTokens(ctx context.Context, log *slog.Logger, authService *auth.Auth) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := authService.DeleteExpiredTokens(ctx)
+			if err != nil {
+				log.Warn("can't delete expired tokens", sl.Err(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// mustLoadKeys loads signing keys from the directory. If it isn't set,
+// the temporary key is generated, so tokens become invalid after restart.
+func mustLoadKeys(log *slog.Logger, cfg config.JWTKeys) *jwt.KeySet {
+	if cfg.Dir == "" {
+		log.Warn("jwt keys dir is not set, temporary key is generated")
+
+		key, err := jwt.GenerateKey(time.Now().UTC().Format("20060102150405"))
+		if err != nil {
+			log.Error("can't generate key", sl.Err(err))
+			os.Exit(1)
+		}
+
+		keys, err := jwt.NewKeySet("", key)
+		if err != nil {
+			log.Error("can't create key set", sl.Err(err))
+			os.Exit(1)
+		}
+
+		return keys
+	}
+
+	keys, err := jwt.LoadKeySet(cfg.Dir, cfg.SigningKeyID)
+	if err != nil {
+		log.Error("can't load keys", slog.String("dir", cfg.Dir), sl.Err(err))
+		os.Exit(1)
+	}
+
+	return keys
+}
+
+func reloadKeys(ctx context.Context, log *slog.Logger, authService *auth.Auth, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := authService.ReloadKeys()
+			if err != nil {
+				log.Warn("can't reload keys", sl.Err(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -7,17 +7,25 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	agentapp "github.com/Prrromanssss/DAEC-fullstack/internal/app/agent"
 	orchestratorapp "github.com/Prrromanssss/DAEC-fullstack/internal/app/orchestrator"
+	authgrpc "github.com/Prrromanssss/DAEC-fullstack/internal/grpc/auth"
 	daecv1 "github.com/Prrromanssss/DAEC-fullstack/internal/protos/gen/go/daec"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/config"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/roles"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/http-server/handlers"
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
 	mwlogger "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/logger"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/setup"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/memorybroker"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/rabbitmq"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 
 	"github.com/go-chi/chi"
@@ -25,6 +33,9 @@ import (
 	"github.com/go-chi/cors"
 )
 
+// minJWKSRefreshInterval limits how often keys are fetched for tokens with unknown kid.
+const minJWKSRefreshInterval = 10 * time.Second
+
 func main() {
 	ctxWithCancel, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -50,7 +61,12 @@ func main() {
 	}
 
 	// Configuration Orchestrator
-	application, err := orchestratorapp.New(log, cfg, dbCfg, cancel)
+	var application *orchestratorapp.App
+	if cfg.Broker.Type == "memory" {
+		application, err = newWithInProcessAgents(ctxWithCancel, log, cfg, dbCfg, cancel)
+	} else {
+		application, err = orchestratorapp.New(log, cfg, dbCfg, cancel)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -72,6 +88,13 @@ func main() {
 
 	grpcClient := daecv1.NewAuthClient(conn)
 
+	// Public keys of the auth service which verify tokens.
+	keys := jwt.NewRemoteKeySet(
+		authgrpc.JWKSFetcher(grpcClient),
+		cfg.JWTKeys.RefreshInterval,
+		minJWKSRefreshInterval,
+	)
+
 	// Configuration HTTP-Server
 	router := chi.NewRouter()
 
@@ -79,7 +102,7 @@ func main() {
 		AllowedOrigins:   []string{"https://*", "http://*"},
 		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -90,29 +113,104 @@ func main() {
 	v1Router.Use(mwlogger.New(log))
 	v1Router.Use(middleware.URLFormat)
 
-	// Expression endpoints
-	v1Router.Post("/expressions", handlers.HandlerCreateExpression(
-		log,
-		dbCfg,
-		cfg.JWTSecret,
-		application.OrchestratorApp,
-		application.Producer,
-	))
-	v1Router.Get("/expressions", handlers.HandlerGetExpressions(log, dbCfg, cfg.JWTSecret))
-
-	// Operation endpoints
-	v1Router.Get("/operations", handlers.HandlerGetOperations(log, dbCfg, cfg.JWTSecret))
-	v1Router.Patch("/operations", handlers.HandlerUpdateOperation(log, dbCfg, cfg.JWTSecret))
-
-	// Agent endpoints
-	v1Router.Get("/agents", handlers.HandlerGetAgents(log, dbCfg))
-
 	// User endpoints
 	v1Router.Post("/login", handlers.HandlerLoginUser(log, dbCfg, grpcClient))
 	v1Router.Post("/register", handlers.HandlerRegisterNewUser(log, dbCfg, grpcClient))
+	v1Router.Post("/refresh", handlers.HandlerRefreshToken(log, grpcClient))
+
+	// Endpoints below require the bearer token.
+	authenticate := mwauth.New(log, keys, dbCfg)
+
+	v1Router.Group(func(r chi.Router) {
+		r.Use(authenticate)
+
+		// Expression endpoints
+		r.Post("/expressions", handlers.HandlerCreateExpression(
+			log,
+			dbCfg,
+			cfg.IdempotencyKeyTTL,
+			application.OrchestratorApp,
+			application.Producer,
+		))
+		r.Get("/expressions", handlers.HandlerGetExpressions(log, dbCfg))
+		r.Post("/expressions:batch", handlers.HandlerCreateBatch(
+			log,
+			dbCfg,
+			application.OrchestratorApp,
+			application.Producer,
+		))
+		r.Get("/batches/{batchID}", handlers.HandlerGetBatchByID(log, dbCfg))
+		r.Get("/expressions/{expressionID}", handlers.HandlerGetExpressionByID(
+			log,
+			dbCfg,
+			application.OrchestratorApp,
+		))
+		r.Post("/expressions/{expressionID}/cancel", handlers.HandlerCancelExpression(
+			log,
+			dbCfg,
+			application.OrchestratorApp,
+		))
+		r.Delete("/expressions/{expressionID}", handlers.HandlerCancelExpression(
+			log,
+			dbCfg,
+			application.OrchestratorApp,
+		))
+
+		// Template endpoints
+		r.Post("/templates", handlers.HandlerCreateTemplate(log, dbCfg))
+		r.Get("/templates", handlers.HandlerGetTemplates(log, dbCfg))
+		r.Get("/templates/{templateID}", handlers.HandlerGetTemplateByID(log, dbCfg))
+		r.Delete("/templates/{templateID}", handlers.HandlerDeleteTemplate(log, dbCfg))
+		r.Post("/templates/{templateID}/expressions", handlers.HandlerEvaluateTemplate(
+			log,
+			dbCfg,
+			application.OrchestratorApp,
+			application.Producer,
+		))
+
+		// Operation endpoints
+		r.Get("/operations", handlers.HandlerGetOperations(log, dbCfg))
+		r.Patch("/operations", handlers.HandlerUpdateOperation(log, dbCfg))
+
+		// Agent endpoints
+		r.With(mwauth.RequireRole(log, roles.Admin)).Get("/agents", handlers.HandlerGetAgents(log, dbCfg))
+		r.With(mwauth.RequireRole(log, roles.Admin)).Post("/agents:drain", handlers.HandlerDrainAgents(log, application.Drain))
+
+		// User endpoints
+		r.Post("/logout", handlers.HandlerLogoutUser(log, grpcClient))
+
+		// Admin endpoints
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(mwauth.RequireRole(log, roles.Admin))
+
+			r.Get("/expressions", handlers.HandlerAdminGetExpressions(log, dbCfg))
+			r.Get("/operations", handlers.HandlerAdminGetDefaultOperations(log, dbCfg))
+			r.Patch("/operations", handlers.HandlerAdminUpdateDefaultOperation(log, dbCfg))
+			r.Post("/agents/{agentID}/kill", handlers.HandlerAdminKillAgent(log, dbCfg, application.Kill))
+			r.Post("/agents/{agentID}/drain", handlers.HandlerAdminDrainAgent(log, dbCfg, application.Drain))
+			r.Get("/users", handlers.HandlerAdminGetUsers(log, dbCfg))
+			r.Patch("/users/{userID}/roles", handlers.HandlerAdminUpdateUserRoles(log, dbCfg))
+			r.Get("/dead-letters", handlers.HandlerAdminGetDeadLetters(log, dbCfg))
+			r.Post("/dead-letters/{deadLetterID}/requeue", handlers.HandlerAdminRequeueDeadLetter(
+				log,
+				dbCfg,
+				application.Requeue,
+			))
+			r.Delete("/dead-letters/{deadLetterID}", handlers.HandlerAdminDeleteDeadLetter(log, dbCfg))
+		})
+	})
+
+	// Event endpoints
+	v1Router.With(
+		mwauth.TokenFromQuery,
+		authenticate,
+	).Get("/events", handlers.HandlerEvents(log, application.OrchestratorApp))
 
 	router.Mount("/v1", v1Router)
 
+	router.Get("/.well-known/jwks.json", handlers.HandlerGetJWKS(log, keys))
+	router.Get("/healthz", handlers.HandlerHealth(log, application.BrokerState))
+
 	srv := &http.Server{
 		Handler:      router,
 		Addr:         cfg.HTTPServer.Address,
@@ -143,3 +241,63 @@ func main() {
 
 	log.Info("http-server stopped")
 }
+
+// newWithInProcessAgents creates Orchestrator app and starts agents in this process,
+// they exchange messages through the in-memory broker.
+func newWithInProcessAgents(
+	ctx context.Context,
+	log *slog.Logger,
+	cfg *config.Config,
+	dbCfg *storage.Storage,
+	cancel context.CancelFunc,
+) (*orchestratorapp.App, error) {
+	broker := memorybroker.New(log, cfg.MaxRetries, cfg.QueueForDeadLetters)
+	go func() {
+		<-ctx.Done()
+		broker.Close()
+	}()
+
+	topology := rabbitmq.NewTopology(cfg.RabbitQueue)
+	broker.Declare(topology.Tasks)
+	broker.Declare(topology.Results)
+	broker.Declare(topology.Heartbeats)
+
+	application, err := orchestratorapp.NewWithBrokers(log, cfg, dbCfg, cancel, orchestratorapp.Brokers{
+		Tasks:          broker.NewProducer(topology.Keys.Tasks),
+		Results:        broker.NewConsumer(cfg.QueueForResultsFromAgents),
+		Heartbeats:     broker.NewConsumer(cfg.QueueForHeartbeats),
+		DeadLetters:    broker.NewConsumer(cfg.QueueForDeadLetters),
+		RequeueResults: broker.NewProducer(topology.Keys.Results),
+		Kill:           broker.NewProducer(topology.Keys.Kill),
+		Drain:          broker.NewProducer(topology.Keys.Drain),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < cfg.Broker.InProcessAgents; i++ {
+		// Failed agent stops only itself.
+		agentCtx, agentCancel := context.WithCancel(ctx)
+
+		agent, err := agentapp.NewWithBrokers(log, cfg, dbCfg, agentCancel, agentapp.Brokers{
+			Results:    broker.NewProducer(topology.Keys.Results),
+			Heartbeats: broker.NewProducer(topology.Keys.Heartbeats),
+			Tasks:      broker.NewConsumer(cfg.QueueForExpressionsToAgents),
+			Control:    broker.NewConsumer(broker.Declare(topology.Control)),
+		})
+		if err != nil {
+			agentCancel()
+			return nil, err
+		}
+
+		go func() {
+			if err := agent.Run(agentCtx); err != nil {
+				log.Warn("in-process agent stopped", sl.Err(err))
+			}
+		}()
+	}
+
+	log.Info("agents run in process", slog.Int("agents", cfg.Broker.InProcessAgents))
+
+	return application, nil
+}
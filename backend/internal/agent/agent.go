@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/big"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,8 +19,6 @@ import (
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
-
-	"github.com/streadway/amqp"
 )
 
 type Agent struct {
@@ -28,6 +28,7 @@ type Agent struct {
 	SimpleComputers chan *messages.ExpressionMessage
 	mu              *sync.Mutex
 	kill            context.CancelFunc
+	computations    map[*messages.ExpressionMessage]context.CancelFunc
 }
 
 // NewAgent creates new Agent.
@@ -59,6 +60,7 @@ func NewAgent(
 		SimpleComputers: make(chan *messages.ExpressionMessage),
 		mu:              &sync.Mutex{},
 		kill:            kill,
+		computations:    make(map[*messages.ExpressionMessage]context.CancelFunc),
 	}, nil
 }
 
@@ -118,26 +120,19 @@ func (a *Agent) Ping(producer brokers.Producer) {
 func (a *Agent) RunSimpleComputer(ctx context.Context, exprMsg *messages.ExpressionMessage) error {
 	const fn = "agent.RunSimpleComputer"
 
-	tokenSplit := strings.Split(exprMsg.Token, " ")
-	if len(tokenSplit) != 3 {
+	tokenSplit := strings.Fields(exprMsg.Token)
+	if len(tokenSplit) < 2 {
 		return fmt.Errorf("invalid token, fn: %s", fn)
 	}
-	oper := tokenSplit[2]
-	if !(oper == "+" || oper == "-" || oper == "/" || oper == "*") {
-		return fmt.Errorf("operation in token doesn't match any of these +, -, /, *, fn: %s", fn)
+	oper := tokenSplit[len(tokenSplit)-1]
+	operands := tokenSplit[:len(tokenSplit)-1]
+	if !isValidOperation(oper, len(operands)) {
+		return fmt.Errorf(
+			"operation %s with %d operands doesn't match any of these +, -, /, *, ^, %%, //, sqrt, abs, pow, min, max, fn: %s",
+			oper, len(operands), fn,
+		)
 	}
 
-	digit1, err := strconv.Atoi(tokenSplit[0])
-	if err != nil {
-		return fmt.Errorf("can't convert int to str: %v, fn: %s", err, fn)
-	}
-	digit2, err := strconv.Atoi(tokenSplit[1])
-	if err != nil {
-		return fmt.Errorf("can't convert int to str: %v, fn: %s", err, fn)
-	}
-	if int(exprMsg.UserID) == 0 {
-		a.log.Warn("", slog.String("oper", oper), slog.Int("userID", int(exprMsg.UserID)))
-	}
 	time_for_oper, err := a.dbConfig.Queries.GetOperationTimeByType(ctx, postgres.GetOperationTimeByTypeParams{
 		OperationType: oper,
 		UserID:        exprMsg.UserID,
@@ -146,15 +141,134 @@ func (a *Agent) RunSimpleComputer(ctx context.Context, exprMsg *messages.Express
 		return fmt.Errorf("can't get execution time by operation type: %v, fn: %s", err, fn)
 	}
 
-	timer := time.NewTimer(time.Duration(time_for_oper) * time.Second)
+	var compute func(ctx context.Context, timer *time.Timer)
+	if exprMsg.Mode == string(postgres.EvaluationModeArbitrary) {
+		digits := make([]*big.Rat, 0, len(operands))
+		for _, operand := range operands {
+			digit, ok := new(big.Rat).SetString(operand)
+			if !ok {
+				return fmt.Errorf("can't convert str to rational number: %s, fn: %s", operand, fn)
+			}
+			digits = append(digits, digit)
+		}
 
-	go simpleComputer(exprMsg, digit1, digit2, oper, timer, a.SimpleComputers)
+		compute = func(ctx context.Context, timer *time.Timer) {
+			exactComputer(ctx, exprMsg, digits, oper, timer, a.SimpleComputers)
+		}
+	} else {
+		digits := make([]float64, 0, len(operands))
+		for _, operand := range operands {
+			digit, err := strconv.ParseFloat(operand, 64)
+			if err != nil {
+				return fmt.Errorf("can't convert str to float: %v, fn: %s", err, fn)
+			}
+			digits = append(digits, digit)
+		}
 
+		compute = func(ctx context.Context, timer *time.Timer) {
+			simpleComputer(ctx, exprMsg, digits, oper, timer, a.SimpleComputers)
+		}
+	}
+
+	// The calculation is counted before it starts, so its result is never handled
+	// while the agent doesn't count it.
 	err = a.dbConfig.Queries.IncrementNumberOfActiveCalculations(ctx, a.AgentID)
 	if err != nil {
 		return fmt.Errorf("can't increment number of active calculations: %v, fn: %s", err, fn)
 	}
 
+	timer := time.NewTimer(time.Duration(time_for_oper) * time.Second)
+
+	go compute(a.startComputation(ctx, exprMsg), timer)
+
+	return nil
+}
+
+// startComputation remembers the running computation of the token,
+// so it can be aborted if the expression is cancelled.
+func (a *Agent) startComputation(ctx context.Context, exprMsg *messages.ExpressionMessage) context.Context {
+	computationCtx, cancel := context.WithCancel(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.computations[exprMsg] = cancel
+
+	return computationCtx
+}
+
+// finishComputation forgets the computation of the token.
+// It returns false if the computation has been already finished or aborted.
+func (a *Agent) finishComputation(exprMsg *messages.ExpressionMessage) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cancel, ok := a.computations[exprMsg]
+	if !ok {
+		return false
+	}
+	cancel()
+	delete(a.computations, exprMsg)
+
+	return true
+}
+
+// CancelFinishedComputations aborts running computations of the expressions
+// which have been cancelled or finished while their tokens were being computed.
+func (a *Agent) CancelFinishedComputations(ctx context.Context) error {
+	const fn = "agent.CancelFinishedComputations"
+
+	a.mu.Lock()
+	exprIDs := make([]int32, 0, len(a.computations))
+	for exprMsg := range a.computations {
+		exprIDs = append(exprIDs, exprMsg.ExpressionID)
+	}
+	a.mu.Unlock()
+
+	if len(exprIDs) == 0 {
+		return nil
+	}
+
+	finishedIDs, err := a.dbConfig.Queries.GetFinishedExpressionIDs(ctx, exprIDs)
+	if err != nil {
+		return fmt.Errorf("can't get finished expressions: %v, fn: %s", err, fn)
+	}
+	if len(finishedIDs) == 0 {
+		return nil
+	}
+
+	finished := make(map[int32]bool, len(finishedIDs))
+	for _, exprID := range finishedIDs {
+		finished[exprID] = true
+	}
+
+	a.mu.Lock()
+	aborted := make([]*messages.ExpressionMessage, 0)
+	for exprMsg := range a.computations {
+		if finished[exprMsg.ExpressionID] {
+			aborted = append(aborted, exprMsg)
+		}
+	}
+	a.mu.Unlock()
+
+	for _, exprMsg := range aborted {
+		if !a.finishComputation(exprMsg) {
+			continue
+		}
+
+		a.log.Info(
+			"abort computation of the finished expression",
+			slog.String("fn", fn),
+			slog.Int("expression ID", int(exprMsg.ExpressionID)),
+			slog.Int("node ID", int(exprMsg.NodeID)),
+		)
+
+		err := a.DecrementActiveComputers(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -267,6 +381,11 @@ func (a *Agent) ConsumeMessageFromComputers(ctx context.Context, result *message
 
 	log.Info("agent consumes message from computers", slog.Any("message", result))
 
+	if !a.finishComputation(result) {
+		log.Info("skip result of the aborted computation", slog.Int("expression ID", int(result.ExpressionID)))
+		return
+	}
+
 	result.AgentID = a.AgentID
 
 	err := producer.PublishExpressionMessage(result)
@@ -294,32 +413,36 @@ func (a *Agent) ConsumeMessageFromComputers(ctx context.Context, result *message
 }
 
 // ConsumeMessageFromOrchestrator hanldes message from Consumer.
-func (a *Agent) ConsumeMessageFromOrchestrator(ctx context.Context, msgFromOrchestrator amqp.Delivery) {
+// The message which can't be computed is failed, so it's retried and then dead-lettered.
+func (a *Agent) ConsumeMessageFromOrchestrator(ctx context.Context, msgFromOrchestrator brokers.Delivery) {
 	const fn = "agent.ConsumeMessageFromOrchestrator"
 
 	log := a.log.With(
 		slog.String("fn", fn),
 	)
 
-	log.Info("agent consumes msg from orchestrator", slog.String("message", string(msgFromOrchestrator.Body)))
+	log.Info("agent consumes msg from orchestrator", slog.String("message", string(msgFromOrchestrator.Body())))
 
 	var exprMsg messages.ExpressionMessage
-	if err := json.Unmarshal(msgFromOrchestrator.Body, &exprMsg); err != nil {
+	if err := json.Unmarshal(msgFromOrchestrator.Body(), &exprMsg); err != nil {
 		log.Error("agent error: failed to parse JSON", sl.Err(err))
-		a.kill()
+		a.failMessage(msgFromOrchestrator, fmt.Errorf("failed to parse JSON: %v", err))
 		return
 	}
 
-	err := msgFromOrchestrator.Ack(false)
+	finishedIDs, err := a.dbConfig.Queries.GetFinishedExpressionIDs(ctx, []int32{exprMsg.ExpressionID})
 	if err != nil {
-		log.Error("agent error: error acknowledging message", sl.Err(err))
-		a.kill()
+		log.Error("agent error: can't check expression status", sl.Err(err))
+		a.failMessage(msgFromOrchestrator, err)
 		return
 	}
-
-	if exprMsg.Kill {
-		log.Error("kill by orchestrator")
-		a.kill()
+	if len(finishedIDs) != 0 {
+		log.Info("drop token of the finished expression", slog.Int("expression ID", int(exprMsg.ExpressionID)))
+		atomic.AddInt32(&a.NumberOfActiveCalculations, -1)
+		err = msgFromOrchestrator.Ack()
+		if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+			log.Error("agent error: error acknowledging message", sl.Err(err))
+		}
 		return
 	}
 
@@ -328,24 +451,31 @@ func (a *Agent) ConsumeMessageFromOrchestrator(ctx context.Context, msgFromOrche
 	err = a.AssignToAgent(ctx, exprMsg.ExpressionID)
 	if err != nil {
 		log.Error("agent error", sl.Err(err))
-		a.kill()
+		a.failMessage(msgFromOrchestrator, err)
 		return
 	}
 
 	err = a.ChangeExpressionStatus(ctx, exprMsg.ExpressionID, "computing")
 	if err != nil {
 		log.Error("agent error", sl.Err(err))
-		a.kill()
+		a.failMessage(msgFromOrchestrator, err)
 		return
 	}
 
 	err = a.RunSimpleComputer(ctx, &exprMsg)
 	if err != nil {
 		log.Error("agent error", sl.Err(err))
-		a.kill()
+		a.failMessage(msgFromOrchestrator, err)
 		return
 	}
 
+	// The computation is started, if the message is stale and delivered again,
+	// the orchestrator skips the second result of the node.
+	err = msgFromOrchestrator.Ack()
+	if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+		log.Error("agent error: error acknowledging message", sl.Err(err))
+	}
+
 	err = a.ChangeAgentStatusToRunningOrSleeping(ctx)
 	if err != nil {
 		log.Error("agent error", sl.Err(err))
@@ -353,3 +483,52 @@ func (a *Agent) ConsumeMessageFromOrchestrator(ctx context.Context, msgFromOrche
 		return
 	}
 }
+
+// ConsumeControlMessage handles kill and drain messages of the orchestrator to all agents or to this one,
+// it returns true if the agent must stop taking new tasks and stop after computing taken ones.
+func (a *Agent) ConsumeControlMessage(msgFromOrchestrator brokers.Delivery) bool {
+	const fn = "agent.ConsumeControlMessage"
+
+	log := a.log.With(
+		slog.String("fn", fn),
+	)
+
+	err := msgFromOrchestrator.Ack()
+	if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+		log.Error("agent error: error acknowledging message", sl.Err(err))
+	}
+
+	var controlMsg messages.ExpressionMessage
+	if err := json.Unmarshal(msgFromOrchestrator.Body(), &controlMsg); err != nil {
+		log.Error("agent error: failed to parse JSON", sl.Err(err))
+		return false
+	}
+
+	// The message with agent ID is addressed to that agent only.
+	if controlMsg.AgentID != 0 && controlMsg.AgentID != a.AgentID {
+		return false
+	}
+
+	switch {
+	case controlMsg.Kill:
+		log.Error("kill by orchestrator")
+		a.kill()
+	case controlMsg.Drain:
+		log.Info("drain by orchestrator")
+		return true
+	}
+
+	return false
+}
+
+// failMessage fails the message which can't be computed instead of stopping the agent.
+func (a *Agent) failMessage(msg brokers.Delivery, reason error) {
+	const fn = "agent.failMessage"
+
+	atomic.AddInt32(&a.NumberOfActiveCalculations, -1)
+
+	err := msg.Fail(reason.Error())
+	if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+		a.log.Error("can't fail message", slog.String("fn", fn), sl.Err(err))
+	}
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/handlers/slogdiscard"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/memorybroker"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+)
+
+func TestConsumeControlMessage(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+	broker := memorybroker.New(log, 1, "dead letters")
+	defer broker.Close()
+	producer := broker.NewProducer("control")
+	consumer := broker.NewConsumer("control")
+
+	testCases := []struct {
+		name      string
+		msg       messages.ExpressionMessage
+		wantDrain bool
+		wantKill  bool
+	}{
+		{name: "Kill of another agent", msg: messages.ExpressionMessage{AgentID: 2, Kill: true}},
+		{name: "Drain of another agent", msg: messages.ExpressionMessage{AgentID: 2, Drain: true}},
+		{name: "Drain of this agent", msg: messages.ExpressionMessage{AgentID: 1, Drain: true}, wantDrain: true},
+		{name: "Drain of all agents", msg: messages.ExpressionMessage{Drain: true}, wantDrain: true},
+		{name: "Kill of this agent", msg: messages.ExpressionMessage{AgentID: 1, Kill: true}, wantKill: true},
+	}
+
+	for _, tc := range testCases {
+		ctx, kill := context.WithCancel(context.Background())
+		a := &Agent{
+			Agent:        postgres.Agent{AgentID: 1},
+			log:          log,
+			mu:           &sync.Mutex{},
+			kill:         kill,
+			computations: make(map[*messages.ExpressionMessage]context.CancelFunc),
+		}
+
+		msg := tc.msg
+		if err := producer.PublishExpressionMessage(&msg); err != nil {
+			t.Fatalf("PublishExpressionMessage returned error: %v", err)
+		}
+
+		select {
+		case delivery := <-consumer.GetMessages():
+			if got := a.ConsumeControlMessage(delivery); got != tc.wantDrain {
+				t.Errorf("%s: ConsumeControlMessage = %v; want %v", tc.name, got, tc.wantDrain)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: no control message in a second", tc.name)
+		}
+
+		if killed := ctx.Err() != nil; killed != tc.wantKill {
+			t.Errorf("%s: agent is killed = %v; want %v", tc.name, killed, tc.wantKill)
+		}
+		kill()
+	}
+}
@@ -1,35 +1,339 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
 )
 
-// simpleComputer calculates a simple expression consisting of 2 operands.
+var (
+	ErrDivisionByZero    = errors.New("division by zero")
+	ErrNegativeSqrt      = errors.New("square root of negative number")
+	ErrNotRealNumber     = errors.New("result is not a real number")
+	ErrIrrationalSqrt    = errors.New("square root is not a rational number")
+	ErrNonIntegerPower   = fmt.Errorf("exponent must be an integer not greater than %d in absolute value", maxExponent)
+	ErrUnknownOperation  = errors.New("unknown operation")
+	errComputationFailed = errors.New("computation failed")
+)
+
+// simpleComputer calculates a simple expression consisting of an operator or a function and its operands.
+// If the expression can't be computed, e.g. because of division by zero, the reason is sent in Error.
+// Nothing is sent if ctx is cancelled before the timer fires.
 func simpleComputer(
+	ctx context.Context,
 	exprMsg *messages.ExpressionMessage,
-	digit1, digit2 int,
+	digits []float64,
 	oper string,
 	timer *time.Timer,
 	res chan<- *messages.ExpressionMessage,
 ) {
-	switch {
-	case oper == "+":
-		<-timer.C
-		exprMsg.Result = digit1 + digit2
-		res <- exprMsg
-	case oper == "-":
-		<-timer.C
-		exprMsg.Result = digit1 - digit2
-		res <- exprMsg
-	case oper == "/":
-		<-timer.C
-		exprMsg.Result = digit1 / digit2
-		res <- exprMsg
-	case oper == "*":
-		<-timer.C
-		exprMsg.Result = digit1 * digit2
+	defer recoverComputer(exprMsg, res)
+
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return
+	case <-timer.C:
+	}
+	result, err := computeFloat(digits, oper)
+	if err != nil {
+		exprMsg.Error = err.Error()
+	} else {
+		exprMsg.Result = formatResult(result)
+		exprMsg.Overflow = !isExact(digits, result)
+	}
+	res <- exprMsg
+}
+
+// exactComputer calculates a simple expression consisting of an operator or a function and its operands
+// with arbitrary precision, so the result never overflows or loses digits.
+// If the expression can't be computed, e.g. because of division by zero, the reason is sent in Error.
+func exactComputer(
+	ctx context.Context,
+	exprMsg *messages.ExpressionMessage,
+	digits []*big.Rat,
+	oper string,
+	timer *time.Timer,
+	res chan<- *messages.ExpressionMessage,
+) {
+	defer recoverComputer(exprMsg, res)
+
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return
+	case <-timer.C:
+	}
+	result, err := computeRat(digits, oper)
+	if err != nil {
+		exprMsg.Error = err.Error()
+	} else {
+		exprMsg.Result = formatRat(result)
+	}
+	res <- exprMsg
+}
+
+// recoverComputer sends the error to the agent if the computer panics,
+// so one bad token doesn't take the whole agent down.
+func recoverComputer(exprMsg *messages.ExpressionMessage, res chan<- *messages.ExpressionMessage) {
+	if r := recover(); r != nil {
+		exprMsg.Result = ""
+		exprMsg.Error = fmt.Sprintf("%v: %v", errComputationFailed, r)
 		res <- exprMsg
 	}
 }
+
+// computeFloat applies the operator or the function to the operands.
+// Infinite results aren't errors, they are reported as overflow by the orchestrator.
+func computeFloat(digits []float64, oper string) (float64, error) {
+	switch oper {
+	case "+":
+		return digits[0] + digits[1], nil
+	case "-":
+		return digits[0] - digits[1], nil
+	case "*":
+		return digits[0] * digits[1], nil
+	case "/":
+		if digits[1] == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return digits[0] / digits[1], nil
+	case "//":
+		if digits[1] == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return math.Floor(digits[0] / digits[1]), nil
+	case "%":
+		if digits[1] == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return digits[0] - digits[1]*math.Floor(digits[0]/digits[1]), nil
+	case "^", "pow":
+		if digits[0] == 0 && digits[1] < 0 {
+			return 0, ErrDivisionByZero
+		}
+		result := math.Pow(digits[0], digits[1])
+		if math.IsNaN(result) {
+			return 0, ErrNotRealNumber
+		}
+		return result, nil
+	case "sqrt":
+		if digits[0] < 0 {
+			return 0, ErrNegativeSqrt
+		}
+		return math.Sqrt(digits[0]), nil
+	case "abs":
+		return math.Abs(digits[0]), nil
+	case "min":
+		result := digits[0]
+		for _, digit := range digits[1:] {
+			result = math.Min(result, digit)
+		}
+		return result, nil
+	case "max":
+		result := digits[0]
+		for _, digit := range digits[1:] {
+			result = math.Max(result, digit)
+		}
+		return result, nil
+	default:
+		return 0, ErrUnknownOperation
+	}
+}
+
+// computeRat applies the operator or the function to the operands with arbitrary precision.
+func computeRat(digits []*big.Rat, oper string) (*big.Rat, error) {
+	result := new(big.Rat)
+
+	switch oper {
+	case "+":
+		return result.Add(digits[0], digits[1]), nil
+	case "-":
+		return result.Sub(digits[0], digits[1]), nil
+	case "*":
+		return result.Mul(digits[0], digits[1]), nil
+	case "/":
+		if digits[1].Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return result.Quo(digits[0], digits[1]), nil
+	case "//":
+		if digits[1].Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return result.SetInt(floorQuo(digits[0], digits[1])), nil
+	case "%":
+		if digits[1].Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return modRat(digits[0], digits[1]), nil
+	case "^", "pow":
+		if !isValidExponent(digits[1]) {
+			return nil, ErrNonIntegerPower
+		}
+		if digits[0].Sign() == 0 && digits[1].Sign() < 0 {
+			return nil, ErrDivisionByZero
+		}
+		return powRat(digits[0], digits[1]), nil
+	case "sqrt":
+		if digits[0].Sign() < 0 {
+			return nil, ErrNegativeSqrt
+		}
+		root := sqrtRat(digits[0])
+		if root == nil {
+			return nil, ErrIrrationalSqrt
+		}
+		return root, nil
+	case "abs":
+		return result.Abs(digits[0]), nil
+	case "min":
+		result.Set(digits[0])
+		for _, digit := range digits[1:] {
+			if digit.Cmp(result) < 0 {
+				result.Set(digit)
+			}
+		}
+		return result, nil
+	case "max":
+		result.Set(digits[0])
+		for _, digit := range digits[1:] {
+			if digit.Cmp(result) > 0 {
+				result.Set(digit)
+			}
+		}
+		return result, nil
+	default:
+		return nil, ErrUnknownOperation
+	}
+}
+
+// isValidOperation checks if the operator or the function can be computed with the given number of operands.
+func isValidOperation(oper string, operands int) bool {
+	switch oper {
+	case "+", "-", "/", "*", "^", "%", "//", "pow":
+		return operands == 2
+	case "sqrt", "abs":
+		return operands == 1
+	case "min", "max":
+		return operands >= 1
+	default:
+		return false
+	}
+}
+
+// maxExponent limits the exponent in arbitrary mode,
+// otherwise a single operation could take all the memory of the agent.
+const maxExponent = 10000
+
+// isValidExponent checks if exponent is an integer not greater than maxExponent in absolute value.
+func isValidExponent(exponent *big.Rat) bool {
+	return exponent.IsInt() && new(big.Int).Abs(exponent.Num()).Cmp(big.NewInt(maxExponent)) <= 0
+}
+
+// powRat raises base to the integer exponent, negative exponent gives the inverse number.
+// Exponent must be checked by isValidExponent before.
+func powRat(base, exponent *big.Rat) *big.Rat {
+	exp := new(big.Int).Abs(exponent.Num())
+	result := new(big.Rat).SetFrac(
+		new(big.Int).Exp(base.Num(), exp, nil),
+		new(big.Int).Exp(base.Denom(), exp, nil),
+	)
+	if exponent.Sign() < 0 {
+		result.Inv(result)
+	}
+
+	return result
+}
+
+// floorQuo returns the quotient of digit1 and digit2 rounded down.
+func floorQuo(digit1, digit2 *big.Rat) *big.Int {
+	quo := new(big.Rat).Quo(digit1, digit2)
+	// Div is the Euclidean division, for the positive denominator it rounds down.
+	return new(big.Int).Div(quo.Num(), quo.Denom())
+}
+
+// sqrtRat returns the square root of digit,
+// if it isn't a rational number like the square root of 2, sqrtRat returns nil.
+func sqrtRat(digit *big.Rat) *big.Rat {
+	if digit.Sign() < 0 {
+		return nil
+	}
+
+	num := new(big.Int).Sqrt(digit.Num())
+	denom := new(big.Int).Sqrt(digit.Denom())
+	result := new(big.Rat).SetFrac(num, denom)
+	if new(big.Rat).Mul(result, result).Cmp(digit) != 0 {
+		return nil
+	}
+
+	return result
+}
+
+// modRat returns the remainder of digit1 divided by digit2,
+// the remainder has the same sign as digit2 like digit1 - digit2*floor(digit1/digit2).
+func modRat(digit1, digit2 *big.Rat) *big.Rat {
+	quo := new(big.Rat).SetInt(floorQuo(digit1, digit2))
+	return quo.Sub(digit1, quo.Mul(quo, digit2))
+}
+
+// maxExactInteger is the greatest integer such that float64 keeps it and all smaller integers exactly.
+const maxExactInteger = 1<<53 - 1
+
+// isExact checks if the operands and the result are in range of integers that float64 keeps exactly,
+// otherwise digits of the result are lost, e.g. 9007199254740993 turns into 9007199254740992.
+func isExact(digits []float64, result float64) bool {
+	for _, digit := range digits {
+		if math.Abs(digit) > maxExactInteger {
+			return false
+		}
+	}
+	return !math.IsNaN(result) && math.Abs(result) <= maxExactInteger
+}
+
+// formatResult formats result as a decimal number.
+// Result is rounded to 15 significant digits, so errors of binary floating point
+// don't get to the user, e.g. 0.1 + 0.2 gives 0.3 instead of 0.30000000000000004.
+// Infinite results are formatted as +Inf and -Inf.
+func formatResult(result float64) string {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(result, 'g', 15, 64), 64)
+	if err != nil {
+		rounded = result
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// formatRat formats result as an integer or a decimal number if it is possible,
+// otherwise as a fraction like 1/3.
+func formatRat(result *big.Rat) string {
+	if result.IsInt() {
+		return result.Num().String()
+	}
+
+	// Fraction has a finite decimal record only if its denominator is 2^n * 5^m,
+	// then it has max(n, m) digits after the point.
+	denom := new(big.Int).Set(result.Denom())
+	places := 0
+	for _, factor := range []int64{2, 5} {
+		divisor := big.NewInt(factor)
+		count := 0
+		for new(big.Int).Mod(denom, divisor).Sign() == 0 {
+			denom.Quo(denom, divisor)
+			count++
+		}
+		if count > places {
+			places = count
+		}
+	}
+
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return result.RatString()
+	}
+
+	return result.FloatString(places)
+}
@@ -0,0 +1,332 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
+)
+
+func TestFormatResult(t *testing.T) {
+	testCases := []struct {
+		name   string
+		result float64
+		want   string
+	}{
+		{
+			name:   "Integer",
+			result: 42,
+			want:   "42",
+		},
+		{
+			name:   "Decimal fraction",
+			result: 7.0 / 2,
+			want:   "3.5",
+		},
+		{
+			name:   "Binary floating point error",
+			result: 0.1 + 0.2,
+			want:   "0.3",
+		},
+		{
+			name:   "Negative number",
+			result: -0.25,
+			want:   "-0.25",
+		},
+		{
+			name:   "Overflow",
+			result: math.Inf(1),
+			want:   "+Inf",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := formatResult(tc.result)
+			if got != tc.want {
+				t.Errorf("formatResult(%v) = %v; want %v", tc.result, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatRat(t *testing.T) {
+	testCases := []struct {
+		name   string
+		result string
+		want   string
+	}{
+		{
+			name:   "Big integer",
+			result: "123456789012345678901234567890",
+			want:   "123456789012345678901234567890",
+		},
+		{
+			name:   "Finite decimal fraction",
+			result: "7/2",
+			want:   "3.5",
+		},
+		{
+			name:   "Finite decimal fraction with powers of 2 and 5",
+			result: "1/40",
+			want:   "0.025",
+		},
+		{
+			name:   "Infinite decimal fraction",
+			result: "-1/3",
+			want:   "-1/3",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, _ := new(big.Rat).SetString(tc.result)
+			got := formatRat(result)
+			if got != tc.want {
+				t.Errorf("formatRat(%v) = %v; want %v", tc.result, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExactOperators(t *testing.T) {
+	testCases := []struct {
+		name   string
+		digit1 string
+		digit2 string
+		oper   string
+		want   string
+	}{
+		{
+			name:   "Power",
+			digit1: "2",
+			digit2: "100",
+			oper:   "^",
+			want:   "1267650600228229401496703205376",
+		},
+		{
+			name:   "Power of fraction",
+			digit1: "1/3",
+			digit2: "2",
+			oper:   "^",
+			want:   "1/9",
+		},
+		{
+			name:   "Negative exponent",
+			digit1: "2",
+			digit2: "-3",
+			oper:   "^",
+			want:   "0.125",
+		},
+		{
+			name:   "Integer division rounds down",
+			digit1: "-7",
+			digit2: "2",
+			oper:   "//",
+			want:   "-4",
+		},
+		{
+			name:   "Integer division of decimals",
+			digit1: "7.5",
+			digit2: "2",
+			oper:   "//",
+			want:   "3",
+		},
+		{
+			name:   "Modulo has sign of divisor",
+			digit1: "-7",
+			digit2: "3",
+			oper:   "%",
+			want:   "2",
+		},
+		{
+			name:   "Modulo of decimals",
+			digit1: "7.5",
+			digit2: "2",
+			oper:   "%",
+			want:   "1.5",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			digit1, _ := new(big.Rat).SetString(tc.digit1)
+			digit2, _ := new(big.Rat).SetString(tc.digit2)
+
+			var got *big.Rat
+			switch tc.oper {
+			case "^":
+				got = powRat(digit1, digit2)
+			case "//":
+				got = new(big.Rat).SetInt(floorQuo(digit1, digit2))
+			case "%":
+				got = modRat(digit1, digit2)
+			}
+			if formatRat(got) != tc.want {
+				t.Errorf("%v %v %v = %v; want %v", tc.digit1, tc.digit2, tc.oper, formatRat(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestIsValidExponent(t *testing.T) {
+	for exponent, want := range map[string]bool{
+		"3":      true,
+		"-3":     true,
+		"0.5":    false,
+		"10000":  true,
+		"100000": false,
+	} {
+		digit, _ := new(big.Rat).SetString(exponent)
+		if got := isValidExponent(digit); got != want {
+			t.Errorf("isValidExponent(%v) = %v; want %v", exponent, got, want)
+		}
+	}
+}
+
+func TestSqrtRat(t *testing.T) {
+	for digit, want := range map[string]string{
+		"16":    "4",
+		"9/4":   "1.5",
+		"0":     "0",
+		"2":     "",
+		"-4":    "",
+		"0.001": "",
+	} {
+		rat, _ := new(big.Rat).SetString(digit)
+		got := ""
+		if result := sqrtRat(rat); result != nil {
+			got = formatRat(result)
+		}
+		if got != want {
+			t.Errorf("sqrtRat(%v) = %v; want %v", digit, got, want)
+		}
+	}
+}
+
+func TestIsExact(t *testing.T) {
+	testCases := []struct {
+		name   string
+		digits []float64
+		result float64
+		want   bool
+	}{
+		{name: "Small integers", digits: []float64{2, 3}, result: 5, want: true},
+		{name: "Max exact integer", digits: []float64{maxExactInteger - 1, 1}, result: maxExactInteger, want: true},
+		{name: "Result loses digits", digits: []float64{maxExactInteger, 2}, result: maxExactInteger + 2, want: false},
+		{name: "Operand loses digits", digits: []float64{1e20, -1e20}, result: 0, want: false},
+		{name: "Infinite result", digits: []float64{1e308, 10}, result: math.Inf(1), want: false},
+		{name: "Not a number", digits: []float64{0}, result: math.NaN(), want: false},
+	}
+
+	for _, tc := range testCases {
+		if got := isExact(tc.digits, tc.result); got != tc.want {
+			t.Errorf("%s: isExact(%v, %v) = %v; want %v", tc.name, tc.digits, tc.result, got, tc.want)
+		}
+	}
+}
+
+func TestIsValidOperation(t *testing.T) {
+	testCases := []struct {
+		oper     string
+		operands int
+		want     bool
+	}{
+		{oper: "+", operands: 2, want: true},
+		{oper: "+", operands: 3, want: false},
+		{oper: "sqrt", operands: 1, want: true},
+		{oper: "pow", operands: 1, want: false},
+		{oper: "max", operands: 5, want: true},
+		{oper: "min", operands: 0, want: false},
+		{oper: "log", operands: 1, want: false},
+	}
+
+	for _, tc := range testCases {
+		if got := isValidOperation(tc.oper, tc.operands); got != tc.want {
+			t.Errorf("isValidOperation(%v, %v) = %v; want %v", tc.oper, tc.operands, got, tc.want)
+		}
+	}
+}
+
+func TestComputeErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		digits []string
+		oper   string
+		err    error
+	}{
+		{name: "Division by zero", digits: []string{"5", "0"}, oper: "/", err: ErrDivisionByZero},
+		{name: "Integer division by zero", digits: []string{"5", "0"}, oper: "//", err: ErrDivisionByZero},
+		{name: "Modulo by zero", digits: []string{"5", "0"}, oper: "%", err: ErrDivisionByZero},
+		{name: "Zero to negative power", digits: []string{"0", "-1"}, oper: "^", err: ErrDivisionByZero},
+		{name: "Square root of negative number", digits: []string{"-4"}, oper: "sqrt", err: ErrNegativeSqrt},
+		{name: "Valid division", digits: []string{"5", "2"}, oper: "/", err: nil},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			floats := make([]float64, 0, len(tc.digits))
+			rats := make([]*big.Rat, 0, len(tc.digits))
+			for _, digit := range tc.digits {
+				rat, _ := new(big.Rat).SetString(digit)
+				value, _ := rat.Float64()
+				floats = append(floats, value)
+				rats = append(rats, rat)
+			}
+
+			if _, err := computeFloat(floats, tc.oper); !errors.Is(err, tc.err) {
+				t.Errorf("computeFloat(%v, %v) error = %v; want %v", tc.digits, tc.oper, err, tc.err)
+			}
+			if _, err := computeRat(rats, tc.oper); !errors.Is(err, tc.err) {
+				t.Errorf("computeRat(%v, %v) error = %v; want %v", tc.digits, tc.oper, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestComputeModeSpecificErrors(t *testing.T) {
+	if _, err := computeFloat([]float64{-8, 1.0 / 3}, "^"); !errors.Is(err, ErrNotRealNumber) {
+		t.Errorf("computeFloat(-8 ^ 1/3) error = %v; want %v", err, ErrNotRealNumber)
+	}
+	if _, err := computeRat([]*big.Rat{big.NewRat(2, 1)}, "sqrt"); !errors.Is(err, ErrIrrationalSqrt) {
+		t.Errorf("computeRat(sqrt(2)) error = %v; want %v", err, ErrIrrationalSqrt)
+	}
+	if _, err := computeRat([]*big.Rat{big.NewRat(2, 1), big.NewRat(1, 2)}, "^"); !errors.Is(err, ErrNonIntegerPower) {
+		t.Errorf("computeRat(2 ^ 1/2) error = %v; want %v", err, ErrNonIntegerPower)
+	}
+}
+
+func TestComputerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	res := make(chan *messages.ExpressionMessage, 1)
+	done := make(chan struct{})
+
+	go func() {
+		simpleComputer(ctx, &messages.ExpressionMessage{}, []float64{1, 2}, "+", time.NewTimer(time.Hour), res)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("simpleComputer doesn't stop after cancel")
+	}
+	if len(res) != 0 {
+		t.Errorf("simpleComputer sent %+v after cancel; want nothing", <-res)
+	}
+
+	res = make(chan *messages.ExpressionMessage, 1)
+	exactComputer(context.Background(), &messages.ExpressionMessage{}, []*big.Rat{big.NewRat(1, 1), big.NewRat(2, 1)}, "+", time.NewTimer(0), res)
+	if got := <-res; got.Result != "3" {
+		t.Errorf("exactComputer(1 + 2) = %v; want 3", got.Result)
+	}
+}
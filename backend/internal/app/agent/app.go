@@ -2,9 +2,11 @@ package agentapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/agent"
@@ -13,7 +15,6 @@ import (
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/rabbitmq"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
-	"github.com/streadway/amqp"
 )
 
 type App struct {
@@ -21,9 +22,23 @@ type App struct {
 	AgentApp    *agent.Agent
 	mu          *sync.Mutex
 	TimeForPing int32
-	amqpConfig  rabbitmq.AMQPConfig
+	amqpConfig  *rabbitmq.AMQPConfig
 	Producer    brokers.Producer
 	Consumer    brokers.Consumer
+	Heartbeats  brokers.Producer
+	Control     brokers.Consumer
+}
+
+// Brokers are producers and consumers of the agent.
+type Brokers struct {
+	// Results publishes results of tokens.
+	Results brokers.Producer
+	// Heartbeats publishes pings.
+	Heartbeats brokers.Producer
+	// Tasks consumes tokens from the orchestrator.
+	Tasks brokers.Consumer
+	// Control consumes kill and drain messages of the orchestrator.
+	Control brokers.Consumer
 }
 
 // MustRun runs Agent and panics if any error occurs.
@@ -33,31 +48,68 @@ func (a *App) MustRun(ctx context.Context) {
 	}
 }
 
-// New creates new Agent app.
+// New creates new Agent app which exchanges messages with the orchestrator through RabbitMQ.
 func New(
 	log *slog.Logger,
 	cfg *config.Config,
 	dbCfg *storage.Storage,
 	cancel context.CancelFunc,
 ) (*App, error) {
-	amqpCfg, err := rabbitmq.NewAMQPConfig(log, cfg.RabbitMQURL)
+	amqpCfg, err := rabbitmq.NewAMQPConfig(log, cfg.RabbitMQURL, rabbitmq.NewOptions(cfg.RabbitQueue))
 	if err != nil {
 		log.Error("can't create NewAMQPConfig", sl.Err(err))
 		return nil, err
 	}
 
-	producer, err := rabbitmq.NewAMQPProducer(log, amqpCfg, cfg.QueueForResultsFromAgents)
+	topology := rabbitmq.NewTopology(cfg.RabbitQueue)
+
+	results, err := rabbitmq.NewAMQPProducer(log, amqpCfg, topology.Keys.Results, topology.Results)
 	if err != nil {
 		log.Error("can't create NewAMQPProducer", sl.Err(err))
 		return nil, err
 	}
 
-	consumer, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, cfg.QueueForExpressionsToAgents)
+	heartbeats, err := rabbitmq.NewAMQPProducer(log, amqpCfg, topology.Keys.Heartbeats, topology.Heartbeats)
+	if err != nil {
+		log.Error("can't create NewAMQPProducer", sl.Err(err))
+		return nil, err
+	}
+
+	tasks, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, topology.Tasks)
+	if err != nil {
+		log.Error("can't create NewAMQPConsumer", sl.Err(err))
+		return nil, err
+	}
+
+	control, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, topology.Control)
 	if err != nil {
 		log.Error("can't create NewAMQPConsumer", sl.Err(err))
 		return nil, err
 	}
 
+	app, err := NewWithBrokers(log, cfg, dbCfg, cancel, Brokers{
+		Results:    results,
+		Heartbeats: heartbeats,
+		Tasks:      tasks,
+		Control:    control,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	app.amqpConfig = amqpCfg
+
+	return app, nil
+}
+
+// NewWithBrokers creates new Agent app which exchanges messages with the orchestrator through brokers.
+func NewWithBrokers(
+	log *slog.Logger,
+	cfg *config.Config,
+	dbCfg *storage.Storage,
+	cancel context.CancelFunc,
+	b Brokers,
+) (*App, error) {
 	ag, err := agent.NewAgent(
 		log,
 		dbCfg,
@@ -73,9 +125,10 @@ func New(
 		AgentApp:    ag,
 		mu:          &sync.Mutex{},
 		TimeForPing: cfg.TimeForPing,
-		amqpConfig:  *amqpCfg,
-		Producer:    producer,
-		Consumer:    consumer,
+		Producer:    b.Results,
+		Consumer:    b.Tasks,
+		Heartbeats:  b.Heartbeats,
+		Control:     b.Control,
 	}, nil
 }
 
@@ -83,31 +136,19 @@ func New(
 // sends pings to Agent Agregator.
 func (a *App) Run(ctx context.Context) error {
 	defer func() {
-		a.amqpConfig.Close()
+		if a.amqpConfig != nil {
+			a.amqpConfig.Close()
+		}
 		a.Producer.Close()
 		a.Consumer.Close()
+		a.Heartbeats.Close()
+		a.Control.Close()
 	}()
 
-	go func() {
-		for msgFromOrchestrator := range a.Consumer.GetMessages() {
-			a.mu.Lock()
-			if a.AgentApp.NumberOfActiveCalculations >= a.AgentApp.NumberOfParallelCalculations {
-				a.mu.Unlock()
-				err := msgFromOrchestrator.Nack(false, true)
-				if err != nil {
-					a.log.Error("can't nack message", sl.Err(err))
-					return
-				}
-				continue // skip the processing of this message and move on to the next one.
-			}
-			a.AgentApp.NumberOfActiveCalculations++
-			a.mu.Unlock()
+	// draining is closed when the orchestrator drains the agent, then it doesn't take new tasks.
+	draining := make(chan struct{})
 
-			go func(msgFromOrchestrator amqp.Delivery) {
-				a.AgentApp.ConsumeMessageFromOrchestrator(ctx, msgFromOrchestrator)
-			}(msgFromOrchestrator)
-		}
-	}()
+	go a.consumeTasks(ctx, draining)
 
 	ticker := time.NewTicker(time.Duration(a.TimeForPing) * time.Second)
 	defer ticker.Stop()
@@ -116,15 +157,77 @@ func (a *App) Run(ctx context.Context) error {
 		select {
 		case result := <-a.AgentApp.SimpleComputers:
 			go a.AgentApp.ConsumeMessageFromComputers(ctx, result, a.Producer)
+		case msgFromOrchestrator, ok := <-a.Control.GetMessages():
+			if !ok {
+				return errors.New("consumer of control messages is closed")
+			}
+			if a.AgentApp.ConsumeControlMessage(msgFromOrchestrator) && !isClosed(draining) {
+				close(draining)
+				// Messages which are taken but not handled are delivered to other agents.
+				a.Consumer.Close()
+			}
 		case <-ctx.Done():
 			a.AgentApp.Terminate()
 			return fmt.Errorf("agent terminated")
 		case <-ticker.C:
-			a.AgentApp.Ping(a.Producer)
+			if isClosed(draining) && atomic.LoadInt32(&a.AgentApp.NumberOfActiveCalculations) == 0 {
+				a.log.Info("agent is drained")
+				a.AgentApp.Terminate()
+				return nil
+			}
+
+			a.AgentApp.Ping(a.Heartbeats)
+			err := a.AgentApp.CancelFinishedComputations(ctx)
+			if err != nil {
+				a.log.Error("can't cancel computations", sl.Err(err))
+			}
 		}
 	}
 }
 
+// consumeTasks takes tasks from the orchestrator till the agent is drained.
+func (a *App) consumeTasks(ctx context.Context, draining <-chan struct{}) {
+	for {
+		var msgFromOrchestrator brokers.Delivery
+		select {
+		case msg, ok := <-a.Consumer.GetMessages():
+			if !ok {
+				return
+			}
+			msgFromOrchestrator = msg
+		case <-draining:
+			return
+		}
+
+		a.mu.Lock()
+		if a.AgentApp.NumberOfActiveCalculations >= a.AgentApp.NumberOfParallelCalculations {
+			a.mu.Unlock()
+			err := msgFromOrchestrator.Nack(true)
+			if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+				a.log.Error("can't nack message", sl.Err(err))
+				return
+			}
+			continue // skip the processing of this message and move on to the next one.
+		}
+		a.AgentApp.NumberOfActiveCalculations++
+		a.mu.Unlock()
+
+		go func(msgFromOrchestrator brokers.Delivery) {
+			a.AgentApp.ConsumeMessageFromOrchestrator(ctx, msgFromOrchestrator)
+		}(msgFromOrchestrator)
+	}
+}
+
+// isClosed checks if the channel is closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
 // Stop stops Agent app.
 func (a *App) Stop(ctx context.Context) {
 	a.AgentApp.Terminate()
@@ -2,6 +2,7 @@ package orchestratorapp
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
@@ -11,21 +12,42 @@ import (
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/pool"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
-	"github.com/streadway/amqp"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/rabbitmq"
 )
 
 type App struct {
-	log                *slog.Logger
-	OrchestratorApp    *orchestrator.Orchestrator
-	workerPool         *pool.MyPool
-	amqpConfig         rabbitmq.AMQPConfig
-	channelForProducer *amqp.Channel
-	channelForConsumer *amqp.Channel
-	Producer           brokers.Producer
-	Consumer           brokers.Consumer
+	log             *slog.Logger
+	OrchestratorApp *orchestrator.Orchestrator
+	workerPool      *pool.MyPool
+	amqpConfig      *rabbitmq.AMQPConfig
+	Producer        brokers.Producer
+	Consumer        brokers.Consumer
+	Heartbeats      brokers.Consumer
+	DeadLetters     brokers.Consumer
+	resultsProducer brokers.Producer
+	// Requeue publishes dead-lettered messages back to their queues by name of the queue.
+	Requeue map[string]brokers.Producer
+	Kill    brokers.Producer
+	Drain   brokers.Producer
+}
+
+// Brokers are producers and consumers of the orchestrator.
+type Brokers struct {
+	// Tasks publishes tokens to agents.
+	Tasks brokers.Producer
+	// Results consumes results of agents.
+	Results brokers.Consumer
+	// Heartbeats consumes pings of agents.
+	Heartbeats brokers.Consumer
+	// DeadLetters consumes messages which failed more than the maximum number of retries.
+	DeadLetters brokers.Consumer
+	// RequeueResults publishes dead-lettered results back to their queue.
+	RequeueResults brokers.Producer
+	// Kill and Drain publish control messages to all agents.
+	Kill  brokers.Producer
+	Drain brokers.Producer
 }
 
 // MustRun runs Orchestrator and panics if any error occurs.
@@ -35,31 +57,90 @@ func (a *App) MustRun(ctx context.Context) {
 	}
 }
 
-// New creates new Orchestrator app.
+// New creates new Orchestrator app which exchanges messages with agents through RabbitMQ.
 func New(
 	log *slog.Logger,
 	cfg *config.Config,
 	dbCfg *storage.Storage,
 	cancel context.CancelFunc,
 ) (*App, error) {
-	amqpCfg, err := rabbitmq.NewAMQPConfig(log, cfg.RabbitMQURL)
+	amqpCfg, err := rabbitmq.NewAMQPConfig(log, cfg.RabbitMQURL, rabbitmq.NewOptions(cfg.RabbitQueue))
 	if err != nil {
 		log.Error("can't create NewAMQPConfig", sl.Err(err))
 		return nil, err
 	}
 
-	producer, err := rabbitmq.NewAMQPProducer(log, amqpCfg, cfg.QueueForExpressionsToAgents)
+	topology := rabbitmq.NewTopology(cfg.RabbitQueue)
+
+	tasks, err := rabbitmq.NewAMQPProducer(log, amqpCfg, topology.Keys.Tasks, topology.Tasks)
 	if err != nil {
 		log.Error("can't create NewAMQPProducer", sl.Err(err))
 		return nil, err
 	}
 
-	consumer, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, cfg.QueueForResultsFromAgents)
+	results, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, topology.Results)
+	if err != nil {
+		log.Error("can't create NewAMQPConsumer", sl.Err(err))
+		return nil, err
+	}
+
+	heartbeats, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, topology.Heartbeats)
+	if err != nil {
+		log.Error("can't create NewAMQPConsumer", sl.Err(err))
+		return nil, err
+	}
+
+	deadLetters, err := rabbitmq.NewAMQPConsumer(log, amqpCfg, brokers.Queue{Name: cfg.QueueForDeadLetters})
 	if err != nil {
 		log.Error("can't create NewAMQPConsumer", sl.Err(err))
 		return nil, err
 	}
 
+	requeueResults, err := rabbitmq.NewAMQPProducer(log, amqpCfg, topology.Keys.Results, topology.Results)
+	if err != nil {
+		log.Error("can't create NewAMQPProducer", sl.Err(err))
+		return nil, err
+	}
+
+	// Control queues are declared by agents.
+	kill, err := rabbitmq.NewAMQPProducer(log, amqpCfg, topology.Keys.Kill)
+	if err != nil {
+		log.Error("can't create NewAMQPProducer", sl.Err(err))
+		return nil, err
+	}
+
+	drain, err := rabbitmq.NewAMQPProducer(log, amqpCfg, topology.Keys.Drain)
+	if err != nil {
+		log.Error("can't create NewAMQPProducer", sl.Err(err))
+		return nil, err
+	}
+
+	app, err := NewWithBrokers(log, cfg, dbCfg, cancel, Brokers{
+		Tasks:          tasks,
+		Results:        results,
+		Heartbeats:     heartbeats,
+		DeadLetters:    deadLetters,
+		RequeueResults: requeueResults,
+		Kill:           kill,
+		Drain:          drain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	app.amqpConfig = amqpCfg
+
+	return app, nil
+}
+
+// NewWithBrokers creates new Orchestrator app which exchanges messages with agents through brokers.
+func NewWithBrokers(
+	log *slog.Logger,
+	cfg *config.Config,
+	dbCfg *storage.Storage,
+	cancel context.CancelFunc,
+	b Brokers,
+) (*App, error) {
 	orc, err := orchestrator.NewOrchestrator(
 		log,
 		dbCfg,
@@ -77,14 +158,20 @@ func New(
 	}
 
 	return &App{
-		log:                log,
-		OrchestratorApp:    orc,
-		workerPool:         workerPool,
-		amqpConfig:         *amqpCfg,
-		channelForProducer: producer.Channel,
-		channelForConsumer: producer.Channel,
-		Producer:           producer,
-		Consumer:           consumer,
+		log:             log,
+		OrchestratorApp: orc,
+		workerPool:      workerPool,
+		Producer:        b.Tasks,
+		Consumer:        b.Results,
+		Heartbeats:      b.Heartbeats,
+		DeadLetters:     b.DeadLetters,
+		resultsProducer: b.RequeueResults,
+		Requeue: map[string]brokers.Producer{
+			cfg.QueueForExpressionsToAgents: b.Tasks,
+			cfg.QueueForResultsFromAgents:   b.RequeueResults,
+		},
+		Kill:  b.Kill,
+		Drain: b.Drain,
 	}, nil
 }
 
@@ -92,9 +179,16 @@ func New(
 // consumes messages from client, manages their job.
 func (a *App) Run(ctx context.Context) error {
 	defer func() {
-		a.amqpConfig.Close()
+		if a.amqpConfig != nil {
+			a.amqpConfig.Close()
+		}
 		a.Producer.Close()
 		a.Consumer.Close()
+		a.Heartbeats.Close()
+		a.DeadLetters.Close()
+		a.resultsProducer.Close()
+		a.Kill.Close()
+		a.Drain.Close()
 		a.workerPool.Stop()
 	}()
 
@@ -114,18 +208,42 @@ func (a *App) Run(ctx context.Context) error {
 		return err
 	}
 
+	// Heartbeats aren't delayed by results of agents.
+	go a.consumeHeartbeats(ctx)
+
 	ticker := time.NewTicker(time.Duration(a.OrchestratorApp.InactiveTimeForAgent) * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		// TODO: Need to syncronize goroutines
-		case msgFromAgents := <-a.Consumer.GetMessages():
+		case msgFromAgents, ok := <-a.Consumer.GetMessages():
+			if !ok {
+				log.Error("consumer is closed")
+
+				return errors.New("consumer is closed")
+			}
 			task := orchestrator.ExecuteWrapper(a.OrchestratorApp, ctx, msgFromAgents, a.Producer)
 			a.workerPool.AddWork(task)
 			time.Sleep(time.Second)
+		case deadLetter, ok := <-a.DeadLetters.GetMessages():
+			if !ok {
+				log.Error("consumer of dead letters is closed")
+
+				return errors.New("consumer of dead letters is closed")
+			}
+			err := a.OrchestratorApp.HandleDeadLetter(ctx, deadLetter)
+			if err != nil {
+				log.Warn("can't save dead letter", sl.Err(err))
+				time.Sleep(time.Second)
+			}
 		case <-ticker.C:
-			err := a.OrchestratorApp.CheckPing(ctx, a.Producer)
+			err := a.OrchestratorApp.CheckDeadlines(ctx)
+			if err != nil {
+				log.Warn("can't check deadlines of expressions", sl.Err(err))
+			}
+
+			err = a.OrchestratorApp.CheckPing(ctx, a.Producer)
 			if err != nil {
 				log.Warn("can't check pings from agents", sl.Err(err))
 			}
@@ -134,6 +252,11 @@ func (a *App) Run(ctx context.Context) error {
 			if err != nil {
 				log.Warn("can't find forgotten expressions", sl.Err(err))
 			}
+
+			err = a.OrchestratorApp.DeleteExpiredIdempotencyKeys(ctx)
+			if err != nil {
+				log.Warn("can't delete expired idempotency keys", sl.Err(err))
+			}
 		case <-ctx.Done():
 			log.Error("orchestrator stopped")
 
@@ -142,27 +265,44 @@ func (a *App) Run(ctx context.Context) error {
 	}
 }
 
-// // Stop stops Orchestrator app.
-func (a *App) Stop(ctx context.Context, cfg *config.Config) {
-	if _, err := a.channelForConsumer.QueuePurge(cfg.QueueForResultsFromAgents, false); err != nil {
-		a.log.Error("can't purged queue", slog.String("queue", cfg.QueueForResultsFromAgents))
-	}
-	if _, err := a.channelForProducer.QueuePurge(cfg.QueueForExpressionsToAgents, false); err != nil {
-		a.log.Error("can't purged queue", slog.String("queue", cfg.QueueForExpressionsToAgents))
+// consumeHeartbeats handles pings of agents till the consumer is closed.
+func (a *App) consumeHeartbeats(ctx context.Context) {
+	const fn = "orchestratorapp.consumeHeartbeats"
+
+	for heartbeat := range a.Heartbeats.GetMessages() {
+		err := a.OrchestratorApp.HandleHeartbeat(ctx, heartbeat)
+		if err != nil {
+			a.log.Warn("can't handle heartbeat", slog.String("fn", fn), sl.Err(err))
+		}
 	}
-	if err := a.Producer.PublishExpressionMessage(&messages.ExpressionMessage{
-		Kill: true,
-	}); err != nil {
-		a.log.Error("can't send kill message to agent")
+}
+
+// BrokerState returns the state of the connection to the message broker.
+func (a *App) BrokerState() string {
+	// The in-memory broker is always available.
+	if a.amqpConfig == nil {
+		return rabbitmq.StateConnected.String()
 	}
-	if err := a.Producer.PublishExpressionMessage(&messages.ExpressionMessage{
-		Kill: true,
-	}); err != nil {
-		a.log.Error("can't send kill message to agent")
+
+	return a.amqpConfig.State().String()
+}
+
+// // Stop stops Orchestrator app.
+func (a *App) Stop(ctx context.Context, cfg *config.Config) {
+	// Durable queues keep tasks and results over restarts, so they aren't purged.
+	// Queues of the in-memory broker are lost on stop anyway.
+	if a.amqpConfig != nil && !cfg.RabbitQueue.Durable {
+		if err := a.amqpConfig.PurgeQueue(cfg.QueueForResultsFromAgents); err != nil {
+			a.log.Error("can't purged queue", slog.String("queue", cfg.QueueForResultsFromAgents))
+		}
+		if err := a.amqpConfig.PurgeQueue(cfg.QueueForExpressionsToAgents); err != nil {
+			a.log.Error("can't purged queue", slog.String("queue", cfg.QueueForExpressionsToAgents))
+		}
 	}
-	if err := a.Producer.PublishExpressionMessage(&messages.ExpressionMessage{
+	// Every agent receives the kill message in its control queue.
+	if err := a.Kill.PublishExpressionMessage(&messages.ExpressionMessage{
 		Kill: true,
 	}); err != nil {
-		a.log.Error("can't send kill message to agent")
+		a.log.Error("can't send kill message to agents", sl.Err(err))
 	}
 }
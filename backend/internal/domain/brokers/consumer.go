@@ -1,9 +1,7 @@
 package brokers
 
-import "github.com/streadway/amqp"
-
 // Consumer is an interface to consume messages from queue.
 type Consumer interface {
-	GetMessages() <-chan amqp.Delivery
+	GetMessages() <-chan Delivery
 	Close()
 }
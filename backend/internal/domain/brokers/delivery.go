@@ -0,0 +1,28 @@
+package brokers
+
+import "errors"
+
+// ErrStaleDelivery is returned by Ack and Nack of the message received before the connection
+// to the broker dropped. The broker delivers the message again, so it mustn't be handled.
+var ErrStaleDelivery = errors.New("delivery is stale")
+
+// Failure describes why the message failed and how many times,
+// it's carried in headers of retried and dead-lettered messages.
+type Failure struct {
+	Queue   string
+	Reason  string
+	Retries int
+}
+
+// Delivery is a message received from queue,
+// it must be acknowledged with Ack, rejected with Nack or failed with Fail.
+type Delivery interface {
+	Body() []byte
+	Ack() error
+	Nack(requeue bool) error
+	// Fail puts the message which can't be handled back to its queue,
+	// after the maximum number of retries it's sent to dead letters.
+	Fail(reason string) error
+	// Failure returns the last failure of the message, it's zero if the message hasn't failed.
+	Failure() Failure
+}
@@ -0,0 +1,67 @@
+package brokers
+
+import "time"
+
+// Queue is the queue with routing keys of messages which are routed to it.
+type Queue struct {
+	// Name of the exclusive queue may be empty, then the broker names it.
+	Name        string
+	RoutingKeys []string
+	// MessageTTL drops messages which aren't consumed in time, zero keeps them.
+	MessageTTL time.Duration
+	// Exclusive queue belongs to one consumer and is deleted with it.
+	Exclusive bool
+}
+
+// Topology is the queues of the orchestrator and agents and routing keys of their messages.
+type Topology struct {
+	Tasks      Queue
+	Results    Queue
+	Heartbeats Queue
+	// Control is the queue of every agent, so kill and drain messages are received by all agents.
+	Control Queue
+	Keys    RoutingKeys
+}
+
+// RoutingKeys route messages of producers to queues.
+type RoutingKeys struct {
+	Tasks      string
+	Results    string
+	Heartbeats string
+	Kill       string
+	Drain      string
+}
+
+// TopologyOptions are names of queues of the Topology and routing keys of their messages.
+type TopologyOptions struct {
+	TasksQueue      string
+	ResultsQueue    string
+	HeartbeatsQueue string
+	HeartbeatTTL    time.Duration
+	Keys            RoutingKeys
+}
+
+// NewTopology creates Topology with the options.
+func NewTopology(opts TopologyOptions) Topology {
+	return Topology{
+		Tasks: Queue{
+			Name:        opts.TasksQueue,
+			RoutingKeys: []string{opts.Keys.Tasks},
+		},
+		Results: Queue{
+			Name:        opts.ResultsQueue,
+			RoutingKeys: []string{opts.Keys.Results},
+		},
+		// Stale pings are useless, so they don't pile up behind the consumer.
+		Heartbeats: Queue{
+			Name:        opts.HeartbeatsQueue,
+			RoutingKeys: []string{opts.Keys.Heartbeats},
+			MessageTTL:  opts.HeartbeatTTL,
+		},
+		Control: Queue{
+			RoutingKeys: []string{opts.Keys.Kill, opts.Keys.Drain},
+			Exclusive:   true,
+		},
+		Keys: opts.Keys,
+	}
+}
@@ -2,16 +2,23 @@ package messages
 
 type ExpressionMessage struct {
 	ExpressionID int32  `json:"expression_id"`
+	NodeID       int32  `json:"node_id"`
 	Token        string `json:"token"`
 	Expression   string `json:"expression"`
-	Result       int    `json:"result"`
-	IsPing       bool   `json:"is_ping"`
-	AgentID      int32  `json:"agent_id"`
-	UserID       int32  `json:"user_id"`
-	Kill         bool   `json:"kill"`
-}
-
-type ResultAndTokenMessage struct {
-	Result string `json:"result"`
-	Token  string `json:"token"`
+	Mode         string `json:"mode"`
+	// Bindings are values of the expression variables.
+	Bindings map[string]string `json:"bindings,omitempty"`
+	Result   string            `json:"result"`
+	// Error is the reason why agent couldn't compute the token, e.g. division by zero.
+	Error string `json:"error,omitempty"`
+	// Overflow means the result of the standard evaluation mode isn't exact,
+	// because it or an operand is out of range of integers that float64 keeps exactly.
+	Overflow bool `json:"overflow,omitempty"`
+	IsPing   bool `json:"is_ping"`
+	// AgentID is the agent which sends the ping or which the kill or drain message is addressed to.
+	AgentID int32 `json:"agent_id"`
+	UserID  int32 `json:"user_id"`
+	Kill    bool  `json:"kill"`
+	// Drain stops the agent when it finishes its computations.
+	Drain bool `json:"drain"`
 }
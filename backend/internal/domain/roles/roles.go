@@ -0,0 +1,9 @@
+package roles
+
+// Roles of users which are stored with them and embedded in JWT claims.
+const (
+	// User can work only with their own expressions, templates and operations.
+	User = "user"
+	// Admin manages agents, default operation times and sees data of all users.
+	Admin = "admin"
+)
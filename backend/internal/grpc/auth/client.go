@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+	daecv1 "github.com/Prrromanssss/DAEC-fullstack/internal/protos/gen/go/daec"
+)
+
+// fetchJWKSTimeout limits the time which the request waits for keys.
+const fetchJWKSTimeout = 5 * time.Second
+
+// JWKSFetcher returns the function which fetches public keys from the auth service.
+func JWKSFetcher(client daecv1.AuthClient) func(ctx context.Context) (jwt.JWKS, error) {
+	return func(ctx context.Context) (jwt.JWKS, error) {
+		ctx, cancel := context.WithTimeout(ctx, fetchJWKSTimeout)
+		defer cancel()
+
+		resp, err := client.GetJWKS(ctx, &daecv1.GetJWKSRequest{})
+		if err != nil {
+			return jwt.JWKS{}, err
+		}
+
+		jwks := jwt.JWKS{Keys: make([]jwt.JWK, 0, len(resp.GetKeys()))}
+		for _, key := range resp.GetKeys() {
+			jwks.Keys = append(jwks.Keys, jwt.JWK{
+				Kty: key.GetKty(),
+				Kid: key.GetKid(),
+				Use: key.GetUse(),
+				Alg: key.GetAlg(),
+				N:   key.GetN(),
+				E:   key.GetE(),
+				Crv: key.GetCrv(),
+				X:   key.GetX(),
+			})
+		}
+
+		return jwks, nil
+	}
+}
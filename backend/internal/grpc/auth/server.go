@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
 	daecv1 "github.com/Prrromanssss/DAEC-fullstack/internal/protos/gen/go/daec"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/services/auth"
 	"google.golang.org/grpc"
@@ -16,12 +17,22 @@ type Auth interface {
 		ctx context.Context,
 		email string,
 		password string,
-	) (token string, err error)
+	) (token string, refreshToken string, err error)
+	Refresh(
+		ctx context.Context,
+		refreshToken string,
+	) (token string, newRefreshToken string, err error)
+	Logout(
+		ctx context.Context,
+		token string,
+		refreshToken string,
+	) error
 	RegisterNewUser(
 		ctx context.Context,
 		email string,
 		password string,
 	) (userID int64, err error)
+	JWKS() (jwt.JWKS, error)
 }
 
 type serverAPI struct {
@@ -41,7 +52,7 @@ func (s *serverAPI) Login(
 		return nil, err
 	}
 
-	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword())
+	token, refreshToken, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword())
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid email or password")
@@ -50,7 +61,77 @@ func (s *serverAPI) Login(
 	}
 
 	return &daecv1.LoginResponse{
-		Token: token,
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *serverAPI) Refresh(
+	ctx context.Context,
+	req *daecv1.RefreshRequest,
+) (*daecv1.RefreshResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	token, refreshToken, err := s.auth.Refresh(ctx, req.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &daecv1.RefreshResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *serverAPI) Logout(
+	ctx context.Context,
+	req *daecv1.LogoutRequest,
+) (*daecv1.LogoutResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	err := s.auth.Logout(ctx, req.GetToken(), req.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &daecv1.LogoutResponse{}, nil
+}
+
+func (s *serverAPI) GetJWKS(
+	ctx context.Context,
+	req *daecv1.GetJWKSRequest,
+) (*daecv1.GetJWKSResponse, error) {
+	jwks, err := s.auth.JWKS()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	keys := make([]*daecv1.JWK, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		keys = append(keys, &daecv1.JWK{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+		})
+	}
+
+	return &daecv1.GetJWKSResponse{
+		Keys: keys,
 	}, nil
 }
 
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/roles"
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+
+	"github.com/go-chi/chi"
+)
+
+// HandlerAdminGetExpressions is a http.Handler to get the page of expressions of all users,
+// the user_id query parameter filters them by the user.
+func HandlerAdminGetExpressions(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminGetExpressions"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID := sql.NullInt32{}
+		if value := r.URL.Query().Get("user_id"); value != "" {
+			id, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				respondWithError(log, w, 400, fmt.Sprintf("invalid user_id: %s", value))
+				return
+			}
+			userID = sql.NullInt32{Int32: int32(id), Valid: true}
+		}
+
+		page, err := parseExpressionsPage(r, userID)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		expressions, nextCursor, err := listExpressions(r.Context(), dbCfg.Queries, page)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get expressions: %v", err))
+			return
+		}
+
+		if nextCursor != "" {
+			w.Header().Set("Link", nextPageLink(r, nextCursor))
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseExpressionsToExpressions(expressions))
+	}
+}
+
+// HandlerAdminGetDefaultOperations is a http.Handler to get execution times
+// which are given to operations of new users.
+func HandlerAdminGetDefaultOperations(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminGetDefaultOperations"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		operations, err := dbCfg.Queries.GetDefaultOperations(r.Context())
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't get default operations: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseDefaultOperationsToDefaultOperations(operations))
+	}
+}
+
+// HandlerAdminUpdateDefaultOperation is a http.Handler to update the default execution time
+// of the operation type. With apply_to_users it's also set for all existing users.
+func HandlerAdminUpdateDefaultOperation(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminUpdateDefaultOperation"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		type parametrs struct {
+			OperationType string `json:"operation_type"`
+			ExecutionTime int32  `json:"execution_time"`
+			ApplyToUsers  bool   `json:"apply_to_users"`
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		params := parametrs{}
+		err := decoder.Decode(&params)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
+		}
+
+		if params.ExecutionTime <= 0 {
+			respondWithError(log, w, 400, "execution_time must be positive")
+			return
+		}
+
+		operation, err := updateDefaultOperation(r.Context(), dbCfg, params.OperationType, params.ExecutionTime, params.ApplyToUsers)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, fmt.Sprintf("unknown operation type: %s", params.OperationType))
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't update default operation: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseDefaultOperationToDefaultOperation(operation))
+	}
+}
+
+// updateDefaultOperation updates the default execution time
+// and the execution times of all users in one transaction.
+func updateDefaultOperation(
+	ctx context.Context,
+	dbCfg *storage.Storage,
+	operationType string,
+	executionTime int32,
+	applyToUsers bool,
+) (postgres.DefaultOperation, error) {
+	tx, err := dbCfg.DB.Begin()
+	if err != nil {
+		return postgres.DefaultOperation{}, err
+	}
+
+	rollback := func(err error) (postgres.DefaultOperation, error) {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			return postgres.DefaultOperation{}, errRollback
+		}
+		return postgres.DefaultOperation{}, err
+	}
+
+	qtx := dbCfg.Queries.WithTx(tx)
+
+	operation, err := qtx.UpdateDefaultOperationTime(ctx, postgres.UpdateDefaultOperationTimeParams{
+		ExecutionTime: executionTime,
+		OperationType: operationType,
+	})
+	if err != nil {
+		return rollback(err)
+	}
+
+	if applyToUsers {
+		err = qtx.UpdateOperationTimeForAllUsers(ctx, postgres.UpdateOperationTimeForAllUsersParams{
+			ExecutionTime: executionTime,
+			OperationType: operationType,
+		})
+		if err != nil {
+			return rollback(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return postgres.DefaultOperation{}, err
+	}
+
+	return operation, nil
+}
+
+// HandlerAdminGetUsers is a http.Handler to get all users with their roles.
+func HandlerAdminGetUsers(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminGetUsers"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		users, err := dbCfg.Queries.GetUsers(r.Context())
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't get users: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseUsersToUsers(users))
+	}
+}
+
+// HandlerAdminUpdateUserRoles is a http.Handler to replace roles of the user.
+// Every user keeps the user role, the new roles are applied on the next login or refresh.
+func HandlerAdminUpdateUserRoles(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminUpdateUserRoles"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		principal, ok := mwauth.PrincipalFromContext(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 32)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("invalid user ID: %s", chi.URLParam(r, "userID")))
+			return
+		}
+
+		type parametrs struct {
+			Roles []string `json:"roles"`
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		params := parametrs{}
+		err = decoder.Decode(&params)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
+		}
+
+		userRoles := []string{roles.User}
+		for _, role := range params.Roles {
+			if role != roles.User && role != roles.Admin {
+				respondWithError(log, w, 400, fmt.Sprintf("unknown role: %s", role))
+				return
+			}
+			if !slices.Contains(userRoles, role) {
+				userRoles = append(userRoles, role)
+			}
+		}
+
+		// Admin can't lock themselves out of the admin endpoints.
+		if int32(userID) == principal.UserID && !slices.Contains(userRoles, roles.Admin) {
+			respondWithError(log, w, 400, "can't revoke admin role from yourself")
+			return
+		}
+
+		user, err := dbCfg.Queries.UpdateUserRoles(r.Context(), postgres.UpdateUserRolesParams{
+			Roles:  userRoles,
+			UserID: int32(userID),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, "user not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't update roles: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseUserToUser(user))
+	}
+}
+
+// HandlerAdminGetDeadLetters is a http.Handler to get messages
+// which failed more than the maximum number of retries.
+func HandlerAdminGetDeadLetters(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminGetDeadLetters"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		deadLetters, err := dbCfg.Queries.GetDeadLetters(r.Context())
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't get dead letters: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseDeadLettersToDeadLetters(deadLetters))
+	}
+}
+
+// HandlerAdminRequeueDeadLetter is a http.Handler to publish the dead-lettered message
+// to its queue again, the message gets all retries back.
+func HandlerAdminRequeueDeadLetter(
+	log *slog.Logger,
+	dbCfg *storage.Storage,
+	producers map[string]brokers.Producer,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminRequeueDeadLetter"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		deadLetterID, err := strconv.ParseInt(chi.URLParam(r, "deadLetterID"), 10, 32)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("invalid dead letter ID: %s", chi.URLParam(r, "deadLetterID")))
+			return
+		}
+
+		deadLetter, err := requeueDeadLetter(r.Context(), dbCfg, producers, int32(deadLetterID))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, "dead letter not found")
+			return
+		}
+		if errors.Is(err, errUnknownQueue) || errors.Is(err, errInvalidMessage) {
+			respondWithError(log, w, 422, err.Error())
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't requeue dead letter: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseDeadLetterToDeadLetter(deadLetter))
+	}
+}
+
+var (
+	errUnknownQueue   = errors.New("dead letter is from unknown queue")
+	errInvalidMessage = errors.New("dead letter isn't a valid message")
+)
+
+// requeueDeadLetter deletes the dead letter and publishes it in one transaction,
+// so the message which isn't published stays in dead letters.
+func requeueDeadLetter(
+	ctx context.Context,
+	dbCfg *storage.Storage,
+	producers map[string]brokers.Producer,
+	deadLetterID int32,
+) (postgres.DeadLetter, error) {
+	tx, err := dbCfg.DB.Begin()
+	if err != nil {
+		return postgres.DeadLetter{}, err
+	}
+
+	rollback := func(err error) (postgres.DeadLetter, error) {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			return postgres.DeadLetter{}, errRollback
+		}
+		return postgres.DeadLetter{}, err
+	}
+
+	qtx := dbCfg.Queries.WithTx(tx)
+
+	deadLetter, err := qtx.DeleteDeadLetter(ctx, deadLetterID)
+	if err != nil {
+		return rollback(err)
+	}
+
+	producer, ok := producers[deadLetter.Queue]
+	if !ok {
+		return rollback(fmt.Errorf("%w: %s", errUnknownQueue, deadLetter.Queue))
+	}
+
+	var msg messages.ExpressionMessage
+	err = json.Unmarshal([]byte(deadLetter.Body), &msg)
+	if err != nil {
+		return rollback(fmt.Errorf("%w: %v", errInvalidMessage, err))
+	}
+
+	err = producer.PublishExpressionMessage(&msg)
+	if err != nil {
+		return rollback(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return postgres.DeadLetter{}, err
+	}
+
+	return deadLetter, nil
+}
+
+// HandlerAdminDeleteDeadLetter is a http.Handler to discard the dead-lettered message.
+func HandlerAdminDeleteDeadLetter(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminDeleteDeadLetter"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		deadLetterID, err := strconv.ParseInt(chi.URLParam(r, "deadLetterID"), 10, 32)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("invalid dead letter ID: %s", chi.URLParam(r, "deadLetterID")))
+			return
+		}
+
+		_, err = dbCfg.Queries.DeleteDeadLetter(r.Context(), int32(deadLetterID))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, "dead letter not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't delete dead letter: %v", err))
+			return
+		}
+
+		w.WriteHeader(204)
+	}
+}
+
+// HandlerAdminKillAgent is a http.Handler to stop the agent at once,
+// its tokens are sent to other agents when the agent misses pings.
+func HandlerAdminKillAgent(log *slog.Logger, dbCfg *storage.Storage, producer brokers.Producer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminKillAgent"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		controlAgent(log, w, r, dbCfg, producer, messages.ExpressionMessage{Kill: true})
+	}
+}
+
+// HandlerAdminDrainAgent is a http.Handler to stop the agent when it finishes computing taken tokens.
+func HandlerAdminDrainAgent(log *slog.Logger, dbCfg *storage.Storage, producer brokers.Producer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerAdminDrainAgent"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		controlAgent(log, w, r, dbCfg, producer, messages.ExpressionMessage{Drain: true})
+	}
+}
+
+// controlAgent publishes the control message to the agent from URL if it isn't terminated.
+func controlAgent(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	dbCfg *storage.Storage,
+	producer brokers.Producer,
+	controlMsg messages.ExpressionMessage,
+) {
+	agentID, err := strconv.ParseInt(chi.URLParam(r, "agentID"), 10, 32)
+	if err != nil {
+		respondWithError(log, w, 400, fmt.Sprintf("invalid agent ID: %s", chi.URLParam(r, "agentID")))
+		return
+	}
+
+	agent, err := dbCfg.Queries.GetAgentByID(r.Context(), int32(agentID))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(log, w, 404, "agent not found")
+		return
+	}
+	if err != nil {
+		respondWithError(log, w, 500, fmt.Sprintf("can't get agent: %v", err))
+		return
+	}
+	if agent.Status == postgres.AgentStatusTerminated {
+		respondWithError(log, w, 409, "agent is already terminated")
+		return
+	}
+
+	controlMsg.AgentID = agent.AgentID
+	err = producer.PublishExpressionMessage(&controlMsg)
+	if err != nil {
+		respondWithError(log, w, 503, fmt.Sprintf("can't send message to agent: %v", err))
+		return
+	}
+
+	w.WriteHeader(202)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/handlers/slogdiscard"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/memorybroker"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+	"github.com/go-chi/chi"
+)
+
+// agentsConnector is the database with the agents table only, it answers every query
+// with the row of the agent which ID is the first argument.
+type agentsConnector map[int64]postgres.AgentStatus
+
+func (c agentsConnector) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c agentsConnector) Driver() driver.Driver                        { return nil }
+
+func (c agentsConnector) Prepare(string) (driver.Stmt, error) { return c, nil }
+func (c agentsConnector) Close() error                        { return nil }
+func (c agentsConnector) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c agentsConnector) NumInput() int                       { return -1 }
+
+func (c agentsConnector) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c agentsConnector) Query(args []driver.Value) (driver.Rows, error) {
+	agentID, _ := args[0].(int64)
+	status, ok := c[agentID]
+	if !ok {
+		return &agentRows{}, nil
+	}
+	now := time.Now()
+	return &agentRows{values: []driver.Value{agentID, int64(5), now, string(status), now, int64(0)}}, nil
+}
+
+type agentRows struct {
+	values []driver.Value
+}
+
+func (r *agentRows) Columns() []string {
+	return []string{
+		"agent_id", "number_of_parallel_calculations", "last_ping",
+		"status", "created_at", "number_of_active_calculations",
+	}
+}
+
+func (r *agentRows) Close() error { return nil }
+
+func (r *agentRows) Next(dest []driver.Value) error {
+	if r.values == nil {
+		return io.EOF
+	}
+	copy(dest, r.values)
+	r.values = nil
+	return nil
+}
+
+type failingProducer struct{}
+
+func (failingProducer) PublishExpressionMessage(*messages.ExpressionMessage) error {
+	return errors.New("broker is unavailable")
+}
+func (failingProducer) Reconnect() (brokers.Producer, error) {
+	return nil, errors.New("broker is unavailable")
+}
+func (failingProducer) Close() {}
+
+func TestControlAgent(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+	db := sql.OpenDB(agentsConnector{
+		1: postgres.AgentStatusRunning,
+		2: postgres.AgentStatusTerminated,
+	})
+	defer db.Close()
+	dbCfg := &storage.Storage{Queries: postgres.New(db), DB: db}
+
+	broker := memorybroker.New(log, 1, "dead letters")
+	defer broker.Close()
+	control := broker.NewConsumer("control")
+
+	testCases := []struct {
+		name     string
+		path     string
+		producer brokers.Producer
+		wantCode int
+		wantMsg  *messages.ExpressionMessage
+	}{
+		{
+			name:     "Kill",
+			path:     "/agents/1/kill",
+			producer: broker.NewProducer("control"),
+			wantCode: 202,
+			wantMsg:  &messages.ExpressionMessage{AgentID: 1, Kill: true},
+		},
+		{
+			name:     "Drain",
+			path:     "/agents/1/drain",
+			producer: broker.NewProducer("control"),
+			wantCode: 202,
+			wantMsg:  &messages.ExpressionMessage{AgentID: 1, Drain: true},
+		},
+		{name: "Invalid ID", path: "/agents/one/kill", producer: broker.NewProducer("control"), wantCode: 400},
+		{name: "Unknown agent", path: "/agents/3/kill", producer: broker.NewProducer("control"), wantCode: 404},
+		{name: "Terminated agent", path: "/agents/2/drain", producer: broker.NewProducer("control"), wantCode: 409},
+		{name: "Broker is unavailable", path: "/agents/1/kill", producer: failingProducer{}, wantCode: 503},
+	}
+
+	for _, tc := range testCases {
+		r := chi.NewRouter()
+		r.Post("/agents/{agentID}/kill", HandlerAdminKillAgent(log, dbCfg, tc.producer))
+		r.Post("/agents/{agentID}/drain", HandlerAdminDrainAgent(log, dbCfg, tc.producer))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, nil))
+		if w.Code != tc.wantCode {
+			t.Errorf("%s: code = %d; want %d", tc.name, w.Code, tc.wantCode)
+		}
+
+		if tc.wantMsg == nil {
+			continue
+		}
+		select {
+		case delivery := <-control.GetMessages():
+			var msg messages.ExpressionMessage
+			if err := json.Unmarshal(delivery.Body(), &msg); err != nil {
+				t.Fatalf("%s: can't decode message: %v", tc.name, err)
+			}
+			if msg.AgentID != tc.wantMsg.AgentID || msg.Kill != tc.wantMsg.Kill || msg.Drain != tc.wantMsg.Drain {
+				t.Errorf("%s: message = %+v; want %+v", tc.name, msg, *tc.wantMsg)
+			}
+			_ = delivery.Ack()
+		case <-time.After(time.Second):
+			t.Errorf("%s: no control message in a second", tc.name)
+		}
+	}
+
+	select {
+	case delivery := <-control.GetMessages():
+		t.Errorf("unexpected control message: %s", delivery.Body())
+	default:
+	}
+}
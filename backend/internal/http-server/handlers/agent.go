@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
 )
@@ -27,3 +29,25 @@ func HandlerGetAgents(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc
 		respondWithJson(log, w, 200, postgres.DatabaseAgentsToAgents(agents))
 	}
 }
+
+// HandlerDrainAgents is a http.Handler to drain all agents:
+// they stop taking new tokens and stop when they finish computing taken ones.
+func HandlerDrainAgents(log *slog.Logger, producer brokers.Producer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerDrainAgents"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		err := producer.PublishExpressionMessage(&messages.ExpressionMessage{
+			Drain: true,
+		})
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't drain agents: %v", err))
+			return
+		}
+
+		w.WriteHeader(202)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+
+	"github.com/go-chi/chi"
+)
+
+// maxBatchSize is the largest number of expressions in one batch.
+const maxBatchSize = 10000
+
+// HandlerCreateBatch is a http.Handler to create many expressions at once.
+// Expressions are created only if all of them are valid, otherwise errors of the invalid ones are returned.
+func HandlerCreateBatch(
+	log *slog.Logger,
+	dbCfg *storage.Storage,
+	orc *orchestrator.Orchestrator,
+	producer brokers.Producer,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerCreateBatch"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		params := make([]expressionParams, 0)
+		err := decoder.Decode(&params)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
+		}
+
+		if len(params) == 0 || len(params) > maxBatchSize {
+			respondWithError(log, w, 400, fmt.Sprintf("batch must have from 1 to %d expressions", maxBatchSize))
+			return
+		}
+
+		type itemError struct {
+			Index int    `json:"index"`
+			Error string `json:"error"`
+			*parser.ParseError
+		}
+
+		newExprs := make([]postgres.CreateExpressionParams, 0, len(params))
+		itemErrors := make([]itemError, 0)
+		for ind, item := range params {
+			newExpr, err := newExpression(userID, item)
+			if err != nil {
+				var parseErr *parser.ParseError
+				errors.As(err, &parseErr)
+				itemErrors = append(itemErrors, itemError{Index: ind, Error: err.Error(), ParseError: parseErr})
+				continue
+			}
+			newExprs = append(newExprs, newExpr)
+		}
+
+		if len(itemErrors) != 0 {
+			type batchErrResponse struct {
+				Error string      `json:"error"`
+				Items []itemError `json:"items"`
+			}
+
+			respondWithJson(log, w, 400, batchErrResponse{
+				Error: fmt.Sprintf("%d of %d expressions are invalid", len(itemErrors), len(params)),
+				Items: itemErrors,
+			})
+			return
+		}
+
+		batch, expressions, err := createBatch(r, dbCfg, userID, newExprs)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't create batch: %v", err))
+			return
+		}
+
+		// Expressions are saved, so they are sent to agents in background
+		// and the client doesn't wait for confirms of all tokens of the batch.
+		// Expressions which can't be sent stay ready and are sent again when the orchestrator restarts.
+		go func() {
+			err := orc.AddTasks(context.WithoutCancel(r.Context()), expressions, producer)
+			if err != nil {
+				log.Error("can't send expressions to agents", slog.Int("batch ID", int(batch.BatchID)), sl.Err(err))
+			}
+		}()
+
+		log.Info("send batch to orchestrator", slog.Int("batch ID", int(batch.BatchID)), slog.Int("size", len(expressions)))
+
+		type batchResponse struct {
+			postgres.BatchTransformed
+			Expressions []postgres.ExpressionTransformed `json:"expressions"`
+		}
+
+		respondWithJson(log, w, 201, batchResponse{
+			BatchTransformed: postgres.DatabaseBatchToBatch(batch, postgres.GetBatchProgressRow{Total: int64(len(expressions))}),
+			Expressions:      postgres.DatabaseExpressionsToExpressions(expressions),
+		})
+	}
+}
+
+// createBatch saves the batch and its expressions in one transaction.
+func createBatch(
+	r *http.Request,
+	dbCfg *storage.Storage,
+	userID int32,
+	newExprs []postgres.CreateExpressionParams,
+) (postgres.Batch, []postgres.Expression, error) {
+	tx, err := dbCfg.DB.Begin()
+	if err != nil {
+		return postgres.Batch{}, nil, err
+	}
+
+	rollback := func(err error) (postgres.Batch, []postgres.Expression, error) {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			return postgres.Batch{}, nil, errRollback
+		}
+		return postgres.Batch{}, nil, err
+	}
+
+	qtx := dbCfg.Queries.WithTx(tx)
+
+	batch, err := qtx.CreateBatch(r.Context(), postgres.CreateBatchParams{
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return rollback(err)
+	}
+
+	expressions := make([]postgres.Expression, 0, len(newExprs))
+	for _, newExpr := range newExprs {
+		newExpr.BatchID = sql.NullInt32{Int32: batch.BatchID, Valid: true}
+		expression, err := qtx.CreateExpression(r.Context(), newExpr)
+		if err != nil {
+			return rollback(err)
+		}
+		expressions = append(expressions, expression)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return postgres.Batch{}, nil, err
+	}
+
+	return batch, expressions, nil
+}
+
+// HandlerGetBatchByID is a http.Handler to get the progress of the batch.
+func HandlerGetBatchByID(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerGetBatchByID"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		batchID, err := strconv.ParseInt(chi.URLParam(r, "batchID"), 10, 32)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("invalid batch ID: %s", chi.URLParam(r, "batchID")))
+			return
+		}
+
+		batch, err := dbCfg.Queries.GetBatchByID(r.Context(), postgres.GetBatchByIDParams{
+			BatchID: int32(batchID),
+			UserID:  userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, "batch not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get batch: %v", err))
+			return
+		}
+
+		progress, err := dbCfg.Queries.GetBatchProgress(r.Context(), sql.NullInt32{Int32: batch.BatchID, Valid: true})
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get progress of batch: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseBatchToBatch(batch, progress))
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator"
+)
+
+// keepAliveInterval is how often the comment is sent to the idle stream,
+// so proxies don't close the connection.
+const keepAliveInterval = 15 * time.Second
+
+// HandlerEvents is a http.Handler to stream changes of the user's expressions
+// and agents as Server-Sent Events.
+func HandlerEvents(log *slog.Logger, orc *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerEvents"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		// The stream lives longer than the write timeout of the server.
+		err := rc.SetWriteDeadline(time.Time{})
+		if err != nil {
+			respondWithError(log, w, 500, fmt.Sprintf("streaming is not supported: %v", err))
+			return
+		}
+
+		events, unsubscribe := orc.Events.Subscribe(userID)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(200)
+		if err := rc.Flush(); err != nil {
+			log.Error("can't flush events", sl.Err(err))
+			return
+		}
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				_, err = fmt.Fprint(w, ": keep-alive\n\n")
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, errMarshal := json.Marshal(event.Data)
+				if errMarshal != nil {
+					log.Error("can't marshal event", sl.Err(errMarshal))
+					continue
+				}
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				log.Info("client closed the stream", sl.Err(err))
+				return
+			}
+		}
+	}
+}
@@ -1,27 +1,35 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
-	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
-	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+
+	"github.com/go-chi/chi"
 )
 
 // HandlerCreateExpression is a http.Handler to create new expression.
+// The request with the Idempotency-Key header which was already used returns
+// the expression created by the first request instead of creating new one.
 func HandlerCreateExpression(
 	log *slog.Logger,
 	dbCfg *storage.Storage,
-	secret string,
+	idempotencyKeyTTL time.Duration,
 	orc *orchestrator.Orchestrator,
 	producer brokers.Producer,
 ) http.HandlerFunc {
@@ -32,79 +40,384 @@ func HandlerCreateExpression(
 			slog.String("fn", fn),
 		)
 
-		userID, err := jwt.GetUidFromJWT(r, secret)
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		wait, err := parseWait(w, r)
 		if err != nil {
-			respondWithError(log, w, 403, "Status Forbidden")
+			respondWithError(log, w, 400, err.Error())
 			return
 		}
 
-		type parametrs struct {
-			Data string `json:"data"`
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't read body: %v", err))
+			return
+		}
+
+		idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			respondWithError(log, w, 400, fmt.Sprintf("%s is longer than %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+		hash := requestHash(body)
+		if idempotencyKey != "" && replayExpression(log, w, r, dbCfg, userID, idempotencyKey, hash) {
+			return
 		}
 
-		decoder := json.NewDecoder(r.Body)
-		params := parametrs{}
-		err = decoder.Decode(&params)
+		params := expressionParams{}
+		err = json.Unmarshal(body, &params)
 		if err != nil {
 			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
 			return
 		}
 
-		parseData, err := parser.ParseExpression(params.Data)
+		newExpr, err := newExpression(userID, params)
+		var parseErr *parser.ParseError
+		if errors.As(err, &parseErr) {
+			respondWithParseError(log, w, err)
+			return
+		}
 		if err != nil {
-			respondWithError(log, w, 400, fmt.Sprintf("error parsing expression: %v", err))
+			respondWithError(log, w, 400, err.Error())
 			return
 		}
 
-		expression, err := dbCfg.Queries.CreateExpression(r.Context(),
-			postgres.CreateExpressionParams{
-				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
-				Data:      params.Data,
-				ParseData: parseData,
-				Status:    "ready_for_computation",
-				UserID:    userID,
-			})
+		var expression postgres.Expression
+		if idempotencyKey == "" {
+			expression, err = dbCfg.Queries.CreateExpression(r.Context(), newExpr)
+		} else {
+			var created bool
+			expression, created, err = createIdempotentExpression(r, dbCfg, newExpr, idempotencyKey, hash, idempotencyKeyTTL)
+			if err == nil && !created {
+				// The concurrent request with the same key has created the expression.
+				if !replayExpression(log, w, r, dbCfg, userID, idempotencyKey, hash) {
+					respondWithError(log, w, 409, "request with the same Idempotency-Key is in progress")
+				}
+				return
+			}
+		}
 		if err != nil {
 			respondWithError(log, w, 400, fmt.Sprintf("can't create expression: %v", err))
 			return
 		}
 
-		msgToQueue := messages.ExpressionMessage{
-			ExpressionID: expression.ExpressionID,
-			Expression:   parseData,
-			UserID:       userID,
+		msgToQueue, err := orchestrator.ExpressionToMessage(expression)
+		if err != nil {
+			respondWithError(log, w, 500, fmt.Sprintf("can't send expression to agents: %v", err))
+			return
 		}
 
-		orc.AddTask(msgToQueue, producer)
+		err = orc.AddTask(r.Context(), msgToQueue, producer)
+		if err != nil {
+			respondWithError(log, w, 500, fmt.Sprintf("can't send expression to agents: %v", err))
+			return
+		}
 
 		log.Info("send message to orchestrator")
 
+		if wait > 0 {
+			expression, err = orc.WaitForExpression(r.Context(), expression.ExpressionID, userID, wait)
+			if err != nil {
+				respondWithError(log, w, 500, fmt.Sprintf("can't wait for expression: %v", err))
+				return
+			}
+		}
+
 		respondWithJson(log, w, 201, postgres.DatabaseExpressionToExpression(expression))
 	}
 }
 
-// HandlerGetExpressions is a http.Handler to get all expressions from storage.
-func HandlerGetExpressions(log *slog.Logger, dbCfg *storage.Storage, secret string) http.HandlerFunc {
+// HandlerGetExpressions is a http.Handler to get the page of expressions from storage.
+// The cursor of the next page is returned in the Link header.
+func HandlerGetExpressions(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		const fn = "handlers.HandlerCreateExpression"
+		const fn = "handlers.HandlerGetExpressions"
 
 		log := log.With(
 			slog.String("fn", fn),
 		)
 
-		userID, err := jwt.GetUidFromJWT(r, secret)
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		page, err := parseExpressionsPage(r, sql.NullInt32{Int32: userID, Valid: true})
 		if err != nil {
-			respondWithError(log, w, 403, "Status Forbidden")
+			respondWithError(log, w, 400, err.Error())
 			return
 		}
 
-		expressions, err := dbCfg.Queries.GetExpressions(r.Context(), userID)
+		expressions, nextCursor, err := listExpressions(r.Context(), dbCfg.Queries, page)
 		if err != nil {
 			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get expressions: %v", err))
 			return
 		}
 
+		if nextCursor != "" {
+			w.Header().Set("Link", nextPageLink(r, nextCursor))
+		}
+
 		respondWithJson(log, w, 200, postgres.DatabaseExpressionsToExpressions(expressions))
 	}
 }
+
+// HandlerGetExpressionByID is a http.Handler to get the expression
+// with the trace of tokens which were computed by agents.
+// With the wait query parameter it waits until the expression is finished.
+func HandlerGetExpressionByID(
+	log *slog.Logger,
+	dbCfg *storage.Storage,
+	orc *orchestrator.Orchestrator,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerGetExpressionByID"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		expressionID, err := expressionIDFromURL(r)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		wait, err := parseWait(w, r)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		expression, err := dbCfg.Queries.GetExpressionByID(r.Context(), expressionID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && expression.UserID != userID) {
+			respondWithError(log, w, 404, "expression not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get expression: %v", err))
+			return
+		}
+
+		if wait > 0 {
+			expression, err = orc.WaitForExpression(r.Context(), expressionID, userID, wait)
+			if err != nil {
+				respondWithError(log, w, 500, fmt.Sprintf("can't wait for expression: %v", err))
+				return
+			}
+		}
+
+		steps, err := dbCfg.Queries.GetExpressionSteps(r.Context(), expressionID)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get steps of expression: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.ExpressionWithStepsTransformed{
+			ExpressionTransformed: postgres.DatabaseExpressionToExpression(expression),
+			Steps:                 postgres.DatabaseExpressionStepsToExpressionSteps(steps),
+		})
+	}
+}
+
+// HandlerCancelExpression is a http.Handler to cancel the expression which is still being computed.
+// Agents drop its tokens and results which come later are ignored.
+func HandlerCancelExpression(
+	log *slog.Logger,
+	dbCfg *storage.Storage,
+	orc *orchestrator.Orchestrator,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerCancelExpression"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		expressionID, err := expressionIDFromURL(r)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		cancelled, err := dbCfg.Queries.MakeExpressionCancelled(r.Context(), postgres.MakeExpressionCancelledParams{
+			UpdatedAt:    time.Now().UTC(),
+			ExpressionID: expressionID,
+			UserID:       userID,
+		})
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't cancel expression: %v", err))
+			return
+		}
+
+		expression, err := dbCfg.Queries.GetExpressionByID(r.Context(), expressionID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && expression.UserID != userID) {
+			respondWithError(log, w, 404, "expression not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get expression: %v", err))
+			return
+		}
+		if cancelled == 0 {
+			respondWithError(log, w, 409, fmt.Sprintf("expression is already finished with status %s", expression.Status))
+			return
+		}
+
+		log.Info("expression is cancelled", slog.Int("expression ID", int(expressionID)))
+
+		orc.NotifyExpression(r.Context(), expressionID)
+
+		respondWithJson(log, w, 200, postgres.DatabaseExpressionToExpression(expression))
+	}
+}
+
+// expressionParams are parameters of the new expression from the user.
+type expressionParams struct {
+	Data     string                 `json:"data"`
+	Mode     string                 `json:"mode"`
+	Bindings map[string]json.Number `json:"bindings"`
+	Deadline *time.Time             `json:"deadline"`
+	Timeout  string                 `json:"timeout"`
+}
+
+// newExpression checks parameters of the new expression and parses it.
+// Errors of the expression itself are returned as *parser.ParseError.
+func newExpression(userID int32, params expressionParams) (postgres.CreateExpressionParams, error) {
+	mode, err := parseEvaluationMode(params.Mode)
+	if err != nil {
+		return postgres.CreateExpressionParams{}, err
+	}
+
+	deadline, err := parseDeadline(params.Deadline, params.Timeout)
+	if err != nil {
+		return postgres.CreateExpressionParams{}, err
+	}
+
+	tree, err := parser.ParseExpression(params.Data)
+	if err != nil {
+		return postgres.CreateExpressionParams{}, err
+	}
+
+	bindings, err := marshalBindings(tree.Variables(), params.Bindings)
+	if err != nil {
+		return postgres.CreateExpressionParams{}, err
+	}
+
+	return postgres.CreateExpressionParams{
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Data:      params.Data,
+		ParseData: tree.Postfix(),
+		Status:    "ready_for_computation",
+		UserID:    userID,
+		Mode:      mode,
+		Bindings:  bindings,
+		Deadline:  deadline,
+	}, nil
+}
+
+// parseEvaluationMode checks the evaluation mode from the user, empty mode is the standard one.
+func parseEvaluationMode(mode string) (postgres.EvaluationMode, error) {
+	if mode == "" {
+		return postgres.EvaluationModeStandard, nil
+	}
+	if mode != string(postgres.EvaluationModeStandard) && mode != string(postgres.EvaluationModeArbitrary) {
+		return "", fmt.Errorf("unknown evaluation mode: %s", mode)
+	}
+	return postgres.EvaluationMode(mode), nil
+}
+
+// parseDeadline returns the deadline of the expression which is given
+// either as the time or as the timeout like "90s", there is no deadline if both are empty.
+func parseDeadline(deadline *time.Time, timeout string) (sql.NullTime, error) {
+	if deadline != nil && timeout != "" {
+		return sql.NullTime{}, errors.New("only one of deadline and timeout can be set")
+	}
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil || duration <= 0 {
+			return sql.NullTime{}, fmt.Errorf("invalid timeout: %s", timeout)
+		}
+		return sql.NullTime{Time: time.Now().UTC().Add(duration), Valid: true}, nil
+	}
+	if deadline != nil {
+		if !deadline.After(time.Now()) {
+			return sql.NullTime{}, errors.New("deadline must be in the future")
+		}
+		return sql.NullTime{Time: deadline.UTC(), Valid: true}, nil
+	}
+	return sql.NullTime{}, nil
+}
+
+// maxWait is the longest time the client can wait for the expression to be finished.
+const maxWait = 2 * time.Minute
+
+// parseWait parses the wait query parameter like "30s" and extends the write deadline of the response,
+// so the server doesn't close the connection while the handler waits for the expression.
+func parseWait(w http.ResponseWriter, r *http.Request) (time.Duration, error) {
+	param := r.URL.Query().Get("wait")
+	if param == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(param)
+	if err != nil || wait < 0 || wait > maxWait {
+		return 0, fmt.Errorf("invalid wait: %s, it must be a duration up to %s", param, maxWait)
+	}
+
+	err = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 5*time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("waiting is not supported: %v", err)
+	}
+
+	return wait, nil
+}
+
+// marshalBindings checks that all variables are bound to numbers
+// and returns bindings of these variables as JSON.
+func marshalBindings(variables []string, bindings map[string]json.Number) (json.RawMessage, error) {
+	values := make(map[string]json.Number, len(variables))
+	unbound := make([]string, 0)
+	for _, variable := range variables {
+		value, ok := bindings[variable]
+		if !ok {
+			unbound = append(unbound, variable)
+			continue
+		}
+		if !parser.IsNumber(value.String()) {
+			return nil, fmt.Errorf("value of variable %s is not a number: %s", variable, value)
+		}
+		values[variable] = value
+	}
+	if len(unbound) > 0 {
+		return nil, fmt.Errorf("unbound variables: %s", strings.Join(unbound, ", "))
+	}
+
+	return json.Marshal(values)
+}
+
+func expressionIDFromURL(r *http.Request) (int32, error) {
+	expressionID, err := strconv.ParseInt(chi.URLParam(r, "expressionID"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expression ID: %s", chi.URLParam(r, "expressionID"))
+	}
+	return int32(expressionID), nil
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/rabbitmq"
+)
+
+// HandlerHealth is a http.Handler to check if the orchestrator can exchange messages with agents.
+// The response is 503 while the connection to the message broker is being restored.
+func HandlerHealth(log *slog.Logger, brokerState func() string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerHealth"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		type healthResponse struct {
+			Status string `json:"status"`
+			Broker string `json:"broker"`
+		}
+
+		state := brokerState()
+		if state != rabbitmq.StateConnected.String() {
+			respondWithJson(log, w, 503, healthResponse{Status: "unavailable", Broker: state})
+			return
+		}
+
+		respondWithJson(log, w, 200, healthResponse{Status: "ok", Broker: state})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	statusIdempotencyMismatch = 422
+)
+
+// requestHash is used to check that the request with the same idempotency key has the same body.
+func requestHash(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+// replayExpression responds with the expression created by the previous request with the same idempotency key.
+// It returns false if the key isn't used yet or is expired.
+func replayExpression(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	dbCfg *storage.Storage,
+	userID int32,
+	key string,
+	hash string,
+) bool {
+	idempotencyKey, err := dbCfg.Queries.GetIdempotencyKey(r.Context(), postgres.GetIdempotencyKeyParams{
+		UserID:    userID,
+		Key:       key,
+		ExpiresAt: time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		respondWithError(log, w, 400, fmt.Sprintf("Couldn't get idempotency key: %v", err))
+		return true
+	}
+
+	if idempotencyKey.RequestHash != hash {
+		respondWithError(log, w, statusIdempotencyMismatch, "Idempotency-Key is already used with another request")
+		return true
+	}
+
+	expression, err := dbCfg.Queries.GetExpressionByID(r.Context(), idempotencyKey.ExpressionID)
+	if err != nil {
+		respondWithError(log, w, 400, fmt.Sprintf("Couldn't get expression: %v", err))
+		return true
+	}
+
+	log.Info("replay expression", slog.Int("expression ID", int(expression.ExpressionID)))
+
+	w.Header().Set(idempotentReplayedHeader, "true")
+	respondWithJson(log, w, 201, postgres.DatabaseExpressionToExpression(expression))
+
+	return true
+}
+
+// createIdempotentExpression saves the expression together with the idempotency key.
+// If the concurrent request with the same key has saved its expression first,
+// nothing is created and false is returned.
+func createIdempotentExpression(
+	r *http.Request,
+	dbCfg *storage.Storage,
+	newExpr postgres.CreateExpressionParams,
+	key string,
+	hash string,
+	ttl time.Duration,
+) (postgres.Expression, bool, error) {
+	tx, err := dbCfg.DB.Begin()
+	if err != nil {
+		return postgres.Expression{}, false, err
+	}
+
+	rollback := func(err error) (postgres.Expression, bool, error) {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			return postgres.Expression{}, false, errRollback
+		}
+		return postgres.Expression{}, false, err
+	}
+
+	qtx := dbCfg.Queries.WithTx(tx)
+
+	expression, err := qtx.CreateExpression(r.Context(), newExpr)
+	if err != nil {
+		return rollback(err)
+	}
+
+	_, err = qtx.CreateIdempotencyKey(r.Context(), postgres.CreateIdempotencyKeyParams{
+		UserID:       newExpr.UserID,
+		Key:          key,
+		RequestHash:  hash,
+		ExpressionID: expression.ExpressionID,
+		CreatedAt:    newExpr.CreatedAt,
+		ExpiresAt:    newExpr.CreatedAt.Add(ttl),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return rollback(nil)
+	}
+	if err != nil {
+		return rollback(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return postgres.Expression{}, false, err
+	}
+
+	return expression, true, nil
+}
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
 )
 
 // The respondWithError function is designed to handle HTTP responses that indicate an error condition.
@@ -22,6 +25,26 @@ func respondWithError(log *slog.Logger, w http.ResponseWriter, code int, msg str
 	})
 }
 
+// The respondWithParseError function responds with 400 and describes where the expression is invalid,
+// so the client can point the user to the wrong character.
+func respondWithParseError(log *slog.Logger, w http.ResponseWriter, err error) {
+	var parseErr *parser.ParseError
+	if !errors.As(err, &parseErr) {
+		respondWithError(log, w, 400, fmt.Sprintf("error parsing expression: %v", err))
+		return
+	}
+
+	type parseErrResponse struct {
+		Error string `json:"error"`
+		*parser.ParseError
+	}
+
+	respondWithJson(log, w, 400, parseErrResponse{
+		Error:      fmt.Sprintf("error parsing expression: %v", err),
+		ParseError: parseErr,
+	})
+}
+
 // The respondWithJson function is a utility function designed to send HTTP responses with JSON content
 func respondWithJson(log *slog.Logger, w http.ResponseWriter, code int, payload interface{}) {
 	data, err := json.Marshal(payload)
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+)
+
+// HandlerGetJWKS is a http.Handler to get public keys which verify tokens.
+func HandlerGetJWKS(log *slog.Logger, keys *jwt.RemoteKeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerGetJWKS"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		jwks, err := keys.JWKS(r.Context())
+		if err != nil {
+			respondWithError(log, w, 503, fmt.Sprintf("can't get keys: %v", err))
+			return
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		respondWithJson(log, w, 200, jwks)
+	}
+}
@@ -6,13 +6,13 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
 )
 
 // HandlerGetOperations is a http.Handler to get all operations from storage.
-func HandlerGetOperations(log *slog.Logger, dbCfg *storage.Storage, secret string) http.HandlerFunc {
+func HandlerGetOperations(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "hadlers.HandlerGetOperations"
 
@@ -20,9 +20,9 @@ func HandlerGetOperations(log *slog.Logger, dbCfg *storage.Storage, secret strin
 			slog.String("fn", fn),
 		)
 
-		userID, err := jwt.GetUidFromJWT(r, secret)
-		if err != nil {
-			respondWithError(log, w, 403, "Status Forbidden")
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
 			return
 		}
 
@@ -37,7 +37,7 @@ func HandlerGetOperations(log *slog.Logger, dbCfg *storage.Storage, secret strin
 }
 
 // HandlerUpdateOperation is a http.Handler to update execution time of the certain operation type.
-func HandlerUpdateOperation(log *slog.Logger, dbCfg *storage.Storage, secret string) http.HandlerFunc {
+func HandlerUpdateOperation(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.HandlerUpdateOperation"
 
@@ -45,9 +45,9 @@ func HandlerUpdateOperation(log *slog.Logger, dbCfg *storage.Storage, secret str
 			slog.String("fn", fn),
 		)
 
-		userID, err := jwt.GetUidFromJWT(r, secret)
-		if err != nil {
-			respondWithError(log, w, 403, "Status Forbidden")
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
 			return
 		}
 
@@ -58,9 +58,10 @@ func HandlerUpdateOperation(log *slog.Logger, dbCfg *storage.Storage, secret str
 
 		decoder := json.NewDecoder(r.Body)
 		params := parametrs{}
-		err = decoder.Decode(&params)
+		err := decoder.Decode(&params)
 		if err != nil {
 			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
 		}
 
 		operation, err := dbCfg.Queries.UpdateOperationTime(r.Context(), postgres.UpdateOperationTimeParams{
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	defaultSort     = "-created_at"
+)
+
+// expressionSorts are sorting orders of expressions, "-" means descending order.
+var expressionSorts = map[string]bool{
+	"created_at":  true,
+	"-created_at": true,
+	"updated_at":  true,
+	"-updated_at": true,
+}
+
+var expressionStatuses = map[postgres.ExpressionStatus]bool{
+	postgres.ExpressionStatusReadyForComputation: true,
+	postgres.ExpressionStatusComputing:           true,
+	postgres.ExpressionStatusResult:              true,
+	postgres.ExpressionStatusTerminated:          true,
+	postgres.ExpressionStatusOverflow:            true,
+	postgres.ExpressionStatusError:               true,
+	postgres.ExpressionStatusCancelled:           true,
+	postgres.ExpressionStatusTimedOut:            true,
+}
+
+// likeEscaper escapes wildcards, so the search text is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// expressionsPage is the page of expressions which is requested by the user.
+type expressionsPage struct {
+	sort   string
+	params postgres.GetExpressionsByCreatedAtDescParams
+}
+
+// parseExpressionsPage parses query parameters of the expressions list:
+// limit, cursor, sort, status, from, to and q.
+// Expressions of all users are listed if userID isn't valid.
+func parseExpressionsPage(r *http.Request, userID sql.NullInt32) (expressionsPage, error) {
+	query := r.URL.Query()
+
+	page := expressionsPage{
+		sort: defaultSort,
+		params: postgres.GetExpressionsByCreatedAtDescParams{
+			UserID:   userID,
+			Statuses: make([]string, 0),
+			Search:   likeEscaper.Replace(query.Get("q")),
+			PageSize: defaultPageSize,
+		},
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		if !expressionSorts[sort] {
+			return expressionsPage{}, fmt.Errorf("invalid sort: %s", sort)
+		}
+		page.sort = sort
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		pageSize, err := strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			return expressionsPage{}, fmt.Errorf("invalid limit: %s, it must be from 1 to %d", limit, maxPageSize)
+		}
+		page.params.PageSize = int32(pageSize)
+	}
+
+	for _, statuses := range query["status"] {
+		for _, status := range strings.Split(statuses, ",") {
+			if !expressionStatuses[postgres.ExpressionStatus(status)] {
+				return expressionsPage{}, fmt.Errorf("invalid status: %s", status)
+			}
+			page.params.Statuses = append(page.params.Statuses, status)
+		}
+	}
+
+	var err error
+	page.params.CreatedFrom, err = parseTimeParam(query.Get("from"))
+	if err != nil {
+		return expressionsPage{}, fmt.Errorf("invalid from: %v", err)
+	}
+	page.params.CreatedTo, err = parseTimeParam(query.Get("to"))
+	if err != nil {
+		return expressionsPage{}, fmt.Errorf("invalid to: %v", err)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		page.params.CursorTime, page.params.CursorID, err = decodeCursor(page.sort, cursor)
+		if err != nil {
+			return expressionsPage{}, err
+		}
+	}
+
+	return page, nil
+}
+
+func parseTimeParam(param string) (sql.NullTime, error) {
+	if param == "" {
+		return sql.NullTime{}, nil
+	}
+	value, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: value.UTC(), Valid: true}, nil
+}
+
+// listExpressions gets the page of expressions and the cursor of the next page,
+// the cursor is empty if it's the last page.
+func listExpressions(
+	ctx context.Context,
+	queries *postgres.Queries,
+	page expressionsPage,
+) ([]postgres.Expression, string, error) {
+	// One more expression is requested to know if there is the next page.
+	params := page.params
+	params.PageSize++
+
+	var expressions []postgres.Expression
+	var err error
+	switch page.sort {
+	case "created_at":
+		expressions, err = queries.GetExpressionsByCreatedAtAsc(ctx, postgres.GetExpressionsByCreatedAtAscParams(params))
+	case "-created_at":
+		expressions, err = queries.GetExpressionsByCreatedAtDesc(ctx, params)
+	case "updated_at":
+		expressions, err = queries.GetExpressionsByUpdatedAtAsc(ctx, postgres.GetExpressionsByUpdatedAtAscParams(params))
+	case "-updated_at":
+		expressions, err = queries.GetExpressionsByUpdatedAtDesc(ctx, postgres.GetExpressionsByUpdatedAtDescParams(params))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(expressions) <= int(page.params.PageSize) {
+		return expressions, "", nil
+	}
+
+	expressions = expressions[:page.params.PageSize]
+	return expressions, encodeCursor(page.sort, expressions[len(expressions)-1]), nil
+}
+
+// encodeCursor returns the position of the expression in the sorting order.
+func encodeCursor(sort string, expression postgres.Expression) string {
+	sortKey := expression.CreatedAt
+	if strings.TrimPrefix(sort, "-") == "updated_at" {
+		sortKey = expression.UpdatedAt
+	}
+	cursor := fmt.Sprintf("%s|%d|%d", sort, sortKey.UnixMicro(), expression.ExpressionID)
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeCursor(sort string, cursor string) (sql.NullTime, int32, error) {
+	errInvalid := errors.New("invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return sql.NullTime{}, 0, errInvalid
+	}
+	parts := strings.Split(string(data), "|")
+	if len(parts) != 3 {
+		return sql.NullTime{}, 0, errInvalid
+	}
+	if parts[0] != sort {
+		return sql.NullTime{}, 0, errors.New("cursor was made for another sort")
+	}
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return sql.NullTime{}, 0, errInvalid
+	}
+	expressionID, err := strconv.ParseInt(parts[2], 10, 32)
+	if err != nil {
+		return sql.NullTime{}, 0, errInvalid
+	}
+
+	return sql.NullTime{Time: time.UnixMicro(micros).UTC(), Valid: true}, int32(expressionID), nil
+}
+
+// nextPageLink returns the Link header value with the URL of the next page.
+func nextPageLink(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	return fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode())
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+)
+
+func TestCursor(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+	expression := postgres.Expression{ExpressionID: 42, CreatedAt: createdAt, UpdatedAt: createdAt.Add(time.Hour)}
+
+	cursor := encodeCursor("-created_at", expression)
+	cursorTime, cursorID, err := decodeCursor("-created_at", cursor)
+	if err != nil {
+		t.Fatalf("decodeCursor(%v) returned error: %v", cursor, err)
+	}
+	if !cursorTime.Valid || !cursorTime.Time.Equal(createdAt) || cursorID != 42 {
+		t.Errorf("decodeCursor(%v) = %v, %v; want %v, 42", cursor, cursorTime.Time, cursorID, createdAt)
+	}
+
+	cursorTime, _, err = decodeCursor("updated_at", encodeCursor("updated_at", expression))
+	if err != nil || !cursorTime.Time.Equal(expression.UpdatedAt) {
+		t.Errorf("decodeCursor for updated_at = %v, %v; want %v", cursorTime.Time, err, expression.UpdatedAt)
+	}
+
+	if _, _, err := decodeCursor("created_at", cursor); err == nil {
+		t.Errorf("decodeCursor with another sort returned no error")
+	}
+	if _, _, err := decodeCursor("-created_at", "not a cursor"); err == nil {
+		t.Errorf("decodeCursor of invalid cursor returned no error")
+	}
+}
+
+func TestParseExpressionsPage(t *testing.T) {
+	userID := sql.NullInt32{Int32: 1, Valid: true}
+
+	testCases := []struct {
+		name  string
+		query string
+		err   bool
+	}{
+		{name: "Defaults", query: ""},
+		{name: "All parameters", query: "?limit=10&sort=updated_at&status=result,error&status=computing&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&q=2%2B2"},
+		{name: "Too big limit", query: "?limit=501", err: true},
+		{name: "Unknown sort", query: "?sort=result", err: true},
+		{name: "Unknown status", query: "?status=done", err: true},
+		{name: "Invalid date", query: "?from=yesterday", err: true},
+		{name: "Invalid cursor", query: "?cursor=abc", err: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseExpressionsPage(httptest.NewRequest("GET", "/v1/expressions"+tc.query, nil), userID)
+			if (err != nil) != tc.err {
+				t.Errorf("parseExpressionsPage(%v) error = %v; want error %v", tc.query, err, tc.err)
+			}
+		})
+	}
+
+	page, _ := parseExpressionsPage(httptest.NewRequest("GET", "/v1/expressions?q=50%25_off", nil), userID)
+	if page.params.Search != `50\%\_off` || page.params.PageSize != defaultPageSize || page.sort != defaultSort {
+		t.Errorf("parseExpressionsPage() = %+v; want escaped search and defaults", page)
+	}
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+
+	"github.com/go-chi/chi"
+)
+
+// HandlerCreateTemplate is a http.Handler to save new parameterized expression.
+func HandlerCreateTemplate(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerCreateTemplate"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		type parametrs struct {
+			Name string `json:"name"`
+			Data string `json:"data"`
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		params := parametrs{}
+		err := decoder.Decode(&params)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
+		}
+
+		if params.Name == "" {
+			respondWithError(log, w, 400, "name of the template is required")
+			return
+		}
+
+		tree, err := parser.ParseExpression(params.Data)
+		if err != nil {
+			respondWithParseError(log, w, err)
+			return
+		}
+
+		template, err := dbCfg.Queries.CreateTemplate(r.Context(), postgres.CreateTemplateParams{
+			UserID:    userID,
+			Name:      params.Name,
+			Data:      params.Data,
+			ParseData: tree.Postfix(),
+			Variables: tree.Variables(),
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't create template: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 201, postgres.DatabaseTemplateToTemplate(template))
+	}
+}
+
+// HandlerGetTemplates is a http.Handler to get all templates of the user.
+func HandlerGetTemplates(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerGetTemplates"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		templates, err := dbCfg.Queries.GetTemplates(r.Context(), userID)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get templates: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseTemplatesToTemplates(templates))
+	}
+}
+
+// HandlerGetTemplateByID is a http.Handler to get the template by its ID.
+func HandlerGetTemplateByID(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerGetTemplateByID"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		templateID, err := templateIDFromURL(r)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		template, err := dbCfg.Queries.GetTemplateByID(r.Context(), postgres.GetTemplateByIDParams{
+			TemplateID: templateID,
+			UserID:     userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, "template not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get template: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, postgres.DatabaseTemplateToTemplate(template))
+	}
+}
+
+// HandlerDeleteTemplate is a http.Handler to delete the template.
+// Expressions which were evaluated from the template are kept.
+func HandlerDeleteTemplate(log *slog.Logger, dbCfg *storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerDeleteTemplate"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		templateID, err := templateIDFromURL(r)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		deleted, err := dbCfg.Queries.DeleteTemplate(r.Context(), postgres.DeleteTemplateParams{
+			TemplateID: templateID,
+			UserID:     userID,
+		})
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't delete template: %v", err))
+			return
+		}
+		if deleted == 0 {
+			respondWithError(log, w, 404, "template not found")
+			return
+		}
+
+		w.WriteHeader(204)
+	}
+}
+
+// HandlerEvaluateTemplate is a http.Handler to create new expression
+// from the template with the given values of its variables.
+func HandlerEvaluateTemplate(
+	log *slog.Logger,
+	dbCfg *storage.Storage,
+	orc *orchestrator.Orchestrator,
+	producer brokers.Producer,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerEvaluateTemplate"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		userID, ok := mwauth.UserID(r.Context())
+		if !ok {
+			respondWithError(log, w, 401, "authentication required")
+			return
+		}
+
+		templateID, err := templateIDFromURL(r)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		type parametrs struct {
+			Mode     string                 `json:"mode"`
+			Bindings map[string]json.Number `json:"bindings"`
+			Deadline *time.Time             `json:"deadline"`
+			Timeout  string                 `json:"timeout"`
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		params := parametrs{}
+		err = decoder.Decode(&params)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
+		}
+
+		mode, err := parseEvaluationMode(params.Mode)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		deadline, err := parseDeadline(params.Deadline, params.Timeout)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		template, err := dbCfg.Queries.GetTemplateByID(r.Context(), postgres.GetTemplateByIDParams{
+			TemplateID: templateID,
+			UserID:     userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(log, w, 404, "template not found")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("Couldn't get template: %v", err))
+			return
+		}
+
+		bindings, err := marshalBindings(template.Variables, params.Bindings)
+		if err != nil {
+			respondWithError(log, w, 400, err.Error())
+			return
+		}
+
+		expression, err := dbCfg.Queries.CreateExpression(r.Context(),
+			postgres.CreateExpressionParams{
+				CreatedAt: time.Now().UTC(),
+				UpdatedAt: time.Now().UTC(),
+				Data:      template.Data,
+				ParseData: template.ParseData,
+				Status:    "ready_for_computation",
+				UserID:    userID,
+				Mode:      mode,
+				Bindings:  bindings,
+				Deadline:  deadline,
+			})
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't create expression: %v", err))
+			return
+		}
+
+		msgToQueue, err := orchestrator.ExpressionToMessage(expression)
+		if err != nil {
+			respondWithError(log, w, 500, fmt.Sprintf("can't send expression to agents: %v", err))
+			return
+		}
+
+		err = orc.AddTask(r.Context(), msgToQueue, producer)
+		if err != nil {
+			respondWithError(log, w, 500, fmt.Sprintf("can't send expression to agents: %v", err))
+			return
+		}
+
+		log.Info("send message to orchestrator")
+
+		respondWithJson(log, w, 201, postgres.DatabaseExpressionToExpression(expression))
+	}
+}
+
+func templateIDFromURL(r *http.Request) (int32, error) {
+	templateID, err := strconv.ParseInt(chi.URLParam(r, "templateID"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid template ID: %s", chi.URLParam(r, "templateID"))
+	}
+	return int32(templateID), nil
+}
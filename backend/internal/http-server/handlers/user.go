@@ -6,8 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
 	daecv1 "github.com/Prrromanssss/DAEC-fullstack/internal/protos/gen/go/daec"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // HandlerLoginUser is a http.Handler to login user.
@@ -93,3 +97,87 @@ func HandlerRegisterNewUser(
 		respondWithJson(log, w, 200, registerResponse)
 	}
 }
+
+// HandlerRefreshToken is a http.Handler to exchange the refresh token for new tokens.
+func HandlerRefreshToken(log *slog.Logger, grpcClient daecv1.AuthClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerRefreshToken"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		type parametrs struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		decoder := json.NewDecoder(r.Body)
+		params := parametrs{}
+		err := decoder.Decode(&params)
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+			return
+		}
+
+		refreshResponse, err := grpcClient.Refresh(r.Context(), &daecv1.RefreshRequest{
+			RefreshToken: params.RefreshToken,
+		})
+		if status.Code(err) == codes.Unauthenticated {
+			respondWithError(log, w, 401, "invalid refresh token")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't refresh token: %v", err))
+			return
+		}
+
+		respondWithJson(log, w, 200, refreshResponse)
+	}
+}
+
+// HandlerLogoutUser is a http.Handler to revoke the token of the request
+// and the refresh token from the body.
+func HandlerLogoutUser(log *slog.Logger, grpcClient daecv1.AuthClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.HandlerLogoutUser"
+
+		log := log.With(
+			slog.String("fn", fn),
+		)
+
+		token, err := jwt.TokenFromHeader(r)
+		if err != nil {
+			respondWithError(log, w, 401, err.Error())
+			return
+		}
+
+		type parametrs struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		params := parametrs{}
+		if r.ContentLength != 0 {
+			decoder := json.NewDecoder(r.Body)
+			err = decoder.Decode(&params)
+			if err != nil {
+				respondWithError(log, w, 400, fmt.Sprintf("error parsing JSON: %v", err))
+				return
+			}
+		}
+
+		_, err = grpcClient.Logout(r.Context(), &daecv1.LogoutRequest{
+			Token:        token,
+			RefreshToken: params.RefreshToken,
+		})
+		if status.Code(err) == codes.Unauthenticated {
+			respondWithError(log, w, 401, "invalid token")
+			return
+		}
+		if err != nil {
+			respondWithError(log, w, 400, fmt.Sprintf("can't logout user: %v", err))
+			return
+		}
+
+		w.WriteHeader(204)
+	}
+}
@@ -0,0 +1,164 @@
+package mwauth
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
+)
+
+// Principal is the authenticated user of the request.
+type Principal struct {
+	UserID int32
+	Email  string
+	Roles  []string
+}
+
+// HasRole checks if the user has the role.
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// RevocationChecker checks if the token was revoked on logout.
+type RevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx which carries the principal.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by the auth middleware.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// UserID returns ID of the authenticated user of the request.
+func UserID(ctx context.Context) (int32, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	return principal.UserID, ok
+}
+
+// New creates http.Handler which lets through only requests with valid and not revoked bearer token
+// and puts the principal of the token into the request context.
+func New(log *slog.Logger, keys jwt.KeyProvider, revocations RevocationChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token, err := jwt.TokenFromHeader(r)
+			if err != nil {
+				respondUnauthorized(log, w, err.Error())
+				return
+			}
+
+			claims, err := jwt.ParseToken(r.Context(), token, keys)
+			if err != nil {
+				log.Info("invalid token", sl.Err(err))
+				respondUnauthorized(log, w, "invalid token")
+				return
+			}
+
+			if claims.ID != "" {
+				revoked, err := revocations.IsTokenRevoked(r.Context(), claims.ID)
+				if err != nil {
+					log.Error("can't check token revocation", sl.Err(err))
+					respondWithError(log, w, http.StatusInternalServerError, "can't check token")
+					return
+				}
+				if revoked {
+					respondUnauthorized(log, w, "token is revoked")
+					return
+				}
+			}
+
+			ctx := WithPrincipal(r.Context(), Principal{
+				UserID: claims.UserID,
+				Email:  claims.Email,
+				Roles:  claims.Roles,
+			})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireRole creates http.Handler which lets through only users with the role,
+// it must be used after New.
+func RequireRole(log *slog.Logger, role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				respondUnauthorized(log, w, "authentication required")
+				return
+			}
+
+			if !principal.HasRole(role) {
+				respondWithError(log, w, http.StatusForbidden, "role "+role+" is required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// TokenFromQuery moves the token from the access_token query parameter to Authorization header,
+// because browsers can't set headers of EventSource requests. It must be used before New.
+func TokenFromQuery(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func respondUnauthorized(log *slog.Logger, w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="daec"`)
+	respondWithError(log, w, http.StatusUnauthorized, msg)
+}
+
+func respondWithError(log *slog.Logger, w http.ResponseWriter, code int, msg string) {
+	type errResponse struct {
+		Error string `json:"error"`
+	}
+
+	data, err := json.Marshal(errResponse{Error: msg})
+	if err != nil {
+		log.Error("failed to marshal JSON response", sl.Err(err))
+		w.WriteHeader(500)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, err = w.Write(data)
+	if err != nil {
+		log.Error("failed to write data", sl.Err(err))
+	}
+}
@@ -0,0 +1,175 @@
+package mwauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mwauth "github.com/Prrromanssss/DAEC-fullstack/internal/http-server/middleware/auth"
+	jwtlib "github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/handlers/slogdiscard"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type revocations map[string]bool
+
+func (r revocations) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	return r[jti], nil
+}
+
+func newKeySet(t *testing.T) *jwtlib.KeySet {
+	t.Helper()
+
+	key, err := jwtlib.GenerateKey("key-1")
+	if err != nil {
+		t.Fatalf("can't generate key: %v", err)
+	}
+	keys, err := jwtlib.NewKeySet("", key)
+	if err != nil {
+		t.Fatalf("can't create key set: %v", err)
+	}
+	return keys
+}
+
+func newToken(t *testing.T, claims jwt.MapClaims, keys *jwtlib.KeySet) string {
+	t.Helper()
+
+	token, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("can't sign token: %v", err)
+	}
+	return token
+}
+
+func TestNew(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+	keys := newKeySet(t)
+	// otherKeys has a key with the same kid, but tokens signed by it must be rejected.
+	otherKeys := newKeySet(t)
+
+	validClaims := jwt.MapClaims{
+		"uid":   1,
+		"email": "user@example.com",
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+
+	testCases := []struct {
+		name   string
+		header string
+		query  string
+		role   string
+		code   int
+	}{
+		{
+			name: "Missing token",
+			code: 401,
+		},
+		{
+			name:   "Wrong scheme",
+			header: "Basic " + newToken(t, validClaims, keys),
+			code:   401,
+		},
+		{
+			name:   "Wrong signature",
+			header: "Bearer " + newToken(t, validClaims, otherKeys),
+			code:   401,
+		},
+		{
+			name:   "Symmetric token",
+			header: "Bearer " + hs256Token(t, validClaims),
+			code:   401,
+		},
+		{
+			name: "Expired token",
+			header: "Bearer " + newToken(t, jwt.MapClaims{
+				"uid": 1,
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			}, keys),
+			code: 401,
+		},
+		{
+			name: "Revoked token",
+			header: "Bearer " + newToken(t, jwt.MapClaims{
+				"jti": "revoked",
+				"uid": 1,
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, keys),
+			code: 401,
+		},
+		{
+			name:   "Valid token",
+			header: "Bearer " + newToken(t, validClaims, keys),
+			code:   200,
+		},
+		{
+			name:  "Token in query",
+			query: newToken(t, validClaims, keys),
+			code:  200,
+		},
+		{
+			name:   "Role is granted",
+			header: "Bearer " + newToken(t, validClaims, keys),
+			role:   "admin",
+			code:   200,
+		},
+		{
+			name: "Role is missing",
+			header: "Bearer " + newToken(t, jwt.MapClaims{
+				"uid": 1,
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, keys),
+			role: "admin",
+			code: 403,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok := mwauth.PrincipalFromContext(r.Context())
+				if !ok || principal.UserID != 1 {
+					t.Errorf("principal = %+v, %v; want user 1", principal, ok)
+				}
+			})
+			if tc.role != "" {
+				handler = mwauth.RequireRole(log, tc.role)(handler)
+			}
+			handler = mwauth.TokenFromQuery(mwauth.New(log, keys, revocations{"revoked": true})(handler))
+
+			r := httptest.NewRequest(http.MethodGet, "/events", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			if tc.query != "" {
+				r.URL.RawQuery = "access_token=" + tc.query
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.code {
+				t.Errorf("status = %d; want %d, body %s", w.Code, tc.code, w.Body.String())
+			}
+			if w.Code == 401 && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
+
+func hs256Token(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "key-1"
+	tokenString, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("can't sign token: %v", err)
+	}
+	return tokenString
+}
@@ -0,0 +1,198 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a set of public keys which verify tokens.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(kid string, public crypto.PublicKey) (JWK, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(public),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported type of key %s: %T", kid, public)
+	}
+}
+
+// PublicKey decodes the public key.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA" && k.Alg == "RS256":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %w", k.Kid, err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key %s", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key %s: kty %s, alg %s", k.Kid, k.Kty, k.Alg)
+	}
+}
+
+// RemoteKeySet is a KeyProvider which fetches public keys from the auth service.
+//
+// Keys are refetched when they are older than refreshInterval or when a token is signed
+// with unknown kid, so rotated keys are picked up without restart.
+type RemoteKeySet struct {
+	fetch              func(ctx context.Context) (JWKS, error)
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewRemoteKeySet creates new RemoteKeySet,
+// the keys are fetched not more often than once per minRefreshInterval.
+func NewRemoteKeySet(
+	fetch func(ctx context.Context) (JWKS, error),
+	refreshInterval time.Duration,
+	minRefreshInterval time.Duration,
+) *RemoteKeySet {
+	return &RemoteKeySet{
+		fetch:              fetch,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		keys:               make(map[string]crypto.PublicKey),
+	}
+}
+
+// PublicKey returns the public key with given kid.
+func (rks *RemoteKeySet) PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	rks.mu.Lock()
+	defer rks.mu.Unlock()
+
+	key, ok := rks.keys[kid]
+	sinceFetch := time.Since(rks.fetchedAt)
+	if ok && sinceFetch < rks.refreshInterval {
+		return key, nil
+	}
+	if !ok && sinceFetch < rks.minRefreshInterval {
+		return nil, ErrKeyNotFound
+	}
+
+	if err := rks.refresh(ctx); err != nil {
+		// The auth service may be unavailable for a while, the known key is still trusted.
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	key, ok = rks.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// JWKS returns the fetched public keys.
+func (rks *RemoteKeySet) JWKS(ctx context.Context) (JWKS, error) {
+	rks.mu.Lock()
+	defer rks.mu.Unlock()
+
+	if time.Since(rks.fetchedAt) >= rks.refreshInterval {
+		if err := rks.refresh(ctx); err != nil && len(rks.keys) == 0 {
+			return JWKS{}, err
+		}
+	}
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(rks.keys))}
+	for kid, key := range rks.keys {
+		jwk, err := newJWK(kid, key)
+		if err != nil {
+			return JWKS{}, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks, nil
+}
+
+func (rks *RemoteKeySet) refresh(ctx context.Context) error {
+	const fn = "jwt.RemoteKeySet.refresh"
+
+	// Failed fetch also counts, so the auth service isn't flooded while it's down.
+	rks.fetchedAt = time.Now()
+
+	jwks, err := rks.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("can't fetch keys: %v, fn: %s", err, fn)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return fmt.Errorf("can't decode key: %v, fn: %s", err, fn)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("auth service returned no keys")
+	}
+
+	rks.keys = keys
+
+	return nil
+}
@@ -1,11 +1,12 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -13,20 +14,20 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// NewToken creates new JWT token.
-func NewToken(user postgres.User, duration time.Duration) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET is not set")
+// NewToken creates new JWT token signed with the current key of the set.
+func NewToken(user postgres.User, duration time.Duration, keys *KeySet) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	tokenString, err := keys.Sign(jwt.MapClaims{
+		"jti":   jti,
 		"uid":   user.UserID,
 		"email": user.Email,
+		"roles": user.Roles,
 		"exp":   time.Now().Add(duration).Unix(),
 	})
-
-	tokenString, err := token.SignedString([]byte(jwtSecret))
 	if err != nil {
 		return "", err
 	}
@@ -34,7 +35,26 @@ func NewToken(user postgres.User, duration time.Duration) (string, error) {
 	return tokenString, nil
 }
 
-func getTokenFromHeader(r *http.Request) (string, error) {
+// newTokenID returns random ID of the token which is used to revoke it.
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Claims are the claims of the user which are carried in JWT token.
+type Claims struct {
+	ID        string
+	UserID    int32
+	Email     string
+	Roles     []string
+	ExpiresAt time.Time
+}
+
+// TokenFromHeader returns the token from "Authorization: Bearer <token>" header.
+func TokenFromHeader(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		return "", fmt.Errorf("authorization header is missing")
@@ -49,39 +69,64 @@ func getTokenFromHeader(r *http.Request) (string, error) {
 	return parts[1], nil // returns token without "Bearer".
 }
 
-func GetUidFromJWT(r *http.Request, secret string) (int32, error) {
-	jwtToken, err := getTokenFromHeader(r)
-	if err != nil {
-		return 0, err
-	}
-
+// ParseToken validates JWT token with the public key from its kid header and returns its claims.
+func ParseToken(ctx context.Context, jwtToken string, keys KeyProvider) (Claims, error) {
 	// Parse JWT Token.
 	token, err := jwt.Parse(jwtToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("jwt token does not contain kid")
+		}
+		return keys.PublicKey(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
 	if err != nil {
-		return 0, err
+		return Claims{}, err
 	}
 
 	if !token.Valid {
-		return 0, errors.New("token is invalid")
+		return Claims{}, errors.New("token is invalid")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.New("error in map claims")
+		return Claims{}, errors.New("error in map claims")
 	}
 
 	userIDFloat, ok := claims["uid"].(float64)
 	if !ok {
-		return 0, errors.New("jwt token does not contain uid")
+		return Claims{}, errors.New("jwt token does not contain uid")
 	}
 
 	userID := int32(userIDFloat)
 
 	if userID == 0 {
-		return 0, errors.New("userID == 0")
+		return Claims{}, errors.New("userID == 0")
+	}
+
+	email, _ := claims["email"].(string)
+	// Token without jti can't be revoked, it's valid till it expires.
+	jti, _ := claims["jti"].(string)
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return Claims{}, errors.New("jwt token does not contain exp")
+	}
+
+	// Tokens without roles are issued to ordinary users.
+	roles := []string{}
+	if rawRoles, ok := claims["roles"].([]interface{}); ok {
+		for _, rawRole := range rawRoles {
+			if role, ok := rawRole.(string); ok {
+				roles = append(roles, role)
+			}
+		}
 	}
 
-	return userID, nil
+	return Claims{
+		ID:        jti,
+		UserID:    userID,
+		Email:     email,
+		Roles:     roles,
+		ExpiresAt: expiresAt.Time,
+	}, nil
 }
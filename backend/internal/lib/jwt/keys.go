@@ -0,0 +1,246 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeySize is the smallest size of RSA key which is accepted for signing.
+const minRSAKeySize = 2048
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyProvider gives public keys to verify tokens by kid from their header.
+type KeyProvider interface {
+	PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// Key is a private key which signs tokens.
+type Key struct {
+	ID      string
+	Private crypto.Signer
+}
+
+// method returns the signing method for the type of the key.
+func (k Key) method() (jwt.SigningMethod, error) {
+	switch private := k.Private.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < minRSAKeySize {
+			return nil, fmt.Errorf("RSA key %s is shorter than %d bits", k.ID, minRSAKeySize)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported type of key %s: %T", k.ID, k.Private)
+	}
+}
+
+// GenerateKey generates new Ed25519 key.
+func GenerateKey(kid string) (Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{ID: kid, Private: private}, nil
+}
+
+// KeySet is a set of keys of the auth service.
+//
+// Tokens are signed with one key, but all keys of the set are published in JWKS,
+// so tokens signed with the previous key are valid till they expire.
+type KeySet struct {
+	mu           sync.RWMutex
+	dir          string
+	signingKeyID string
+	keys         map[string]Key
+	signingKey   Key
+}
+
+// NewKeySet creates the set of the given keys,
+// the key with signingKeyID or the last key if it's empty signs tokens.
+func NewKeySet(signingKeyID string, keys ...Key) (*KeySet, error) {
+	ks := &KeySet{signingKeyID: signingKeyID}
+	if err := ks.setKeys(keys); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// LoadKeySet loads keys from PEM files of the directory, kid of the key is the name of its file
+// without .pem extension. If signingKeyID is empty,
+// the key which is the last in lexicographic order signs tokens.
+func LoadKeySet(dir string, signingKeyID string) (*KeySet, error) {
+	ks := &KeySet{dir: dir, signingKeyID: signingKeyID}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Reload rereads keys from the directory, so the keys can be rotated without restart.
+// If the new keys are invalid, the old ones are kept.
+func (ks *KeySet) Reload() error {
+	const fn = "jwt.KeySet.Reload"
+
+	if ks.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("can't list keys: %v, fn: %s", err, fn)
+	}
+
+	keys := make([]Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return fmt.Errorf("can't read key: %v, fn: %s", err, fn)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := ks.setKeys(keys); err != nil {
+		return fmt.Errorf("%v, fn: %s", err, fn)
+	}
+
+	return nil
+}
+
+func (ks *KeySet) setKeys(keys []Key) error {
+	if len(keys) == 0 {
+		return errors.New("no keys")
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+
+	byID := make(map[string]Key, len(keys))
+	for _, key := range keys {
+		if _, err := key.method(); err != nil {
+			return err
+		}
+		byID[key.ID] = key
+	}
+
+	signingKey := keys[len(keys)-1]
+	if ks.signingKeyID != "" {
+		key, ok := byID[ks.signingKeyID]
+		if !ok {
+			return fmt.Errorf("signing key %s: %w", ks.signingKeyID, ErrKeyNotFound)
+		}
+		signingKey = key
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys = byID
+	ks.signingKey = signingKey
+
+	return nil
+}
+
+// Sign signs the token with the current signing key and puts its kid into the header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.signingKey
+	ks.mu.RUnlock()
+
+	method, err := key.method()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Private)
+}
+
+// PublicKey returns the public key with given kid.
+func (ks *KeySet) PublicKey(_ context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key.Private.Public(), nil
+}
+
+// JWKS returns public keys of the set.
+func (ks *KeySet) JWKS() (JWKS, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk, err := newJWK(key.ID, key.Private.Public())
+		if err != nil {
+			return JWKS{}, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks, nil
+}
+
+func readKey(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("key %s is not PEM encoded", kid)
+	}
+
+	var private any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("key %s has unsupported PEM type %s", kid, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("can't parse key %s: %w", kid, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return Key{}, fmt.Errorf("key %s can't sign", kid)
+	}
+
+	return Key{ID: kid, Private: signer}, nil
+}
@@ -0,0 +1,128 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+)
+
+func writeKey(t *testing.T, dir, kid string, private any) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("can't marshal key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("can't write key: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	user := postgres.User{UserID: 1, Email: "user@example.com"}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("can't generate RSA key: %v", err)
+	}
+	writeKey(t, dir, "2024-01", rsaKey)
+
+	keys, err := jwt.LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet returned error: %v", err)
+	}
+
+	remote := jwt.NewRemoteKeySet(func(ctx context.Context) (jwt.JWKS, error) {
+		return keys.JWKS()
+	}, time.Hour, 0)
+
+	oldToken, err := jwt.NewToken(user, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("NewToken returned error: %v", err)
+	}
+
+	claims, err := jwt.ParseToken(ctx, oldToken, remote)
+	if err != nil {
+		t.Fatalf("ParseToken(RS256 token) returned error: %v", err)
+	}
+	if claims.UserID != 1 || claims.Email != "user@example.com" || claims.ID == "" {
+		t.Errorf("claims = %+v; want user 1 with jti", claims)
+	}
+
+	// The new key takes over signing, the old one still verifies issued tokens.
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("can't generate Ed25519 key: %v", err)
+	}
+	writeKey(t, dir, "2024-02", edKey)
+	if err := keys.Reload(); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+
+	newToken, err := jwt.NewToken(user, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("NewToken returned error: %v", err)
+	}
+	if _, err := jwt.ParseToken(ctx, newToken, remote); err != nil {
+		t.Errorf("ParseToken(token with new kid) returned error: %v", err)
+	}
+	if _, err := jwt.ParseToken(ctx, oldToken, remote); err != nil {
+		t.Errorf("ParseToken(token with old kid) returned error: %v", err)
+	}
+
+	jwks, err := keys.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS returned error: %v", err)
+	}
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Alg != "RS256" || jwks.Keys[1].Alg != "EdDSA" {
+		t.Errorf("JWKS = %+v; want RS256 and EdDSA keys", jwks)
+	}
+
+	// Tokens of the removed key aren't accepted after the keys are refetched.
+	if err := os.Remove(filepath.Join(dir, "2024-01.pem")); err != nil {
+		t.Fatalf("can't remove key: %v", err)
+	}
+	if err := keys.Reload(); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+	remote = jwt.NewRemoteKeySet(func(ctx context.Context) (jwt.JWKS, error) {
+		return keys.JWKS()
+	}, time.Hour, 0)
+	if _, err := jwt.ParseToken(ctx, oldToken, remote); err == nil {
+		t.Errorf("ParseToken(token of removed key) returned no error")
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := jwt.LoadKeySet(dir, ""); err == nil {
+		t.Errorf("LoadKeySet(empty dir) returned no error")
+	}
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("can't generate RSA key: %v", err)
+	}
+	writeKey(t, dir, "small", smallKey)
+	if _, err := jwt.LoadKeySet(dir, ""); err == nil {
+		t.Errorf("LoadKeySet(1024-bit RSA key) returned no error")
+	}
+
+	if _, err := jwt.LoadKeySet(t.TempDir(), "missing"); err == nil {
+		t.Errorf("LoadKeySet(missing signing key) returned no error")
+	}
+}
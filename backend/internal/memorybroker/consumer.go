@@ -0,0 +1,24 @@
+package memorybroker
+
+import (
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+)
+
+type Consumer struct {
+	queue *queue
+}
+
+// NewConsumer creates new Consumer of the queue.
+func (b *Broker) NewConsumer(queueName string) *Consumer {
+	return &Consumer{
+		queue: b.queue(queueName),
+	}
+}
+
+// GetMessages returns messages of the queue, the channel is closed with the Broker.
+func (c *Consumer) GetMessages() <-chan brokers.Delivery {
+	return c.queue.messages
+}
+
+// Close does nothing, queues are closed with the Broker.
+func (c *Consumer) Close() {}
@@ -0,0 +1,80 @@
+package memorybroker
+
+import (
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+)
+
+var ErrAlreadyAcknowledged = errors.New("message is already acknowledged")
+
+// delivery is a message taken from the queue.
+type delivery struct {
+	body    []byte
+	failure brokers.Failure
+	queue   *queue
+	expires time.Time
+	done    atomic.Bool
+}
+
+// Body returns the content of the message.
+func (d *delivery) Body() []byte {
+	return d.body
+}
+
+// Ack acknowledges the message.
+func (d *delivery) Ack() error {
+	if !d.done.CompareAndSwap(false, true) {
+		return ErrAlreadyAcknowledged
+	}
+
+	return nil
+}
+
+// Nack rejects the message, with requeue it's put to the end of the queue.
+func (d *delivery) Nack(requeue bool) error {
+	if !d.done.CompareAndSwap(false, true) {
+		return ErrAlreadyAcknowledged
+	}
+
+	if requeue {
+		d.queue.push(d.body, d.failure)
+	}
+
+	return nil
+}
+
+// Fail puts the message to the end of its queue or to dead letters
+// if it has failed more than maxRetries times.
+func (d *delivery) Fail(reason string) error {
+	if !d.done.CompareAndSwap(false, true) {
+		return ErrAlreadyAcknowledged
+	}
+
+	failure := d.failure
+	failure.Reason = reason
+	failure.Retries++
+	if failure.Queue == "" {
+		failure.Queue = d.queue.name
+	}
+
+	broker := d.queue.broker
+	if failure.Retries > broker.maxRetries {
+		broker.log.Warn("message is dead-lettered", slog.String("queue", failure.Queue), slog.String("reason", reason))
+		broker.queue(broker.deadLetterQueue).push(d.body, failure)
+
+		return nil
+	}
+
+	broker.queue(failure.Queue).push(d.body, failure)
+
+	return nil
+}
+
+// Failure returns the last failure of the message.
+func (d *delivery) Failure() brokers.Failure {
+	return d.failure
+}
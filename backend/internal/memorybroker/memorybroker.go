@@ -0,0 +1,197 @@
+package memorybroker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+)
+
+var ErrClosed = errors.New("broker is closed")
+
+// Broker is an in-process message broker with queues on Go channels,
+// so the orchestrator and agents can run in one process without RabbitMQ.
+// Like the direct exchange of RabbitMQ it routes messages to all queues
+// which are bound with the routing key, messages with the key which isn't bound
+// go to the queue with the same name. Messages aren't persisted and are lost when the process stops.
+type Broker struct {
+	log             *slog.Logger
+	maxRetries      int
+	deadLetterQueue string
+
+	mu        sync.Mutex
+	queues    map[string]*queue
+	bindings  map[string][]*queue
+	unnamed   int
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates new Broker, failed messages are retried maxRetries times
+// and then put to deadLetterQueue.
+func New(log *slog.Logger, maxRetries int, deadLetterQueue string) *Broker {
+	return &Broker{
+		log:             log,
+		maxRetries:      maxRetries,
+		deadLetterQueue: deadLetterQueue,
+		queues:          make(map[string]*queue),
+		bindings:        make(map[string][]*queue),
+		done:            make(chan struct{}),
+	}
+}
+
+// Declare declares the queue and binds it with its routing keys.
+// It returns the name of the queue which is given by the Broker to the queue without name.
+func (b *Broker) Declare(queue brokers.Queue) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	name := queue.Name
+	if name == "" {
+		b.unnamed++
+		name = fmt.Sprintf("queue-%d", b.unnamed)
+	}
+
+	q := b.declare(name)
+	q.ttl = queue.MessageTTL
+	for _, routingKey := range queue.RoutingKeys {
+		b.bindings[routingKey] = append(b.bindings[routingKey], q)
+	}
+
+	return name
+}
+
+// route returns queues of the routing key.
+func (b *Broker) route(routingKey string) []*queue {
+	b.mu.Lock()
+	queues, ok := b.bindings[routingKey]
+	b.mu.Unlock()
+
+	if !ok {
+		return []*queue{b.queue(routingKey)}
+	}
+
+	return queues
+}
+
+// queue returns the queue with the name and declares it if it doesn't exist.
+func (b *Broker) queue(name string) *queue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.declare(name)
+}
+
+// declare returns the queue with the name and creates it if it doesn't exist, it must be called with mu.
+func (b *Broker) declare(name string) *queue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &queue{
+			broker:   b,
+			name:     name,
+			ready:    make(chan struct{}, 1),
+			messages: make(chan brokers.Delivery),
+		}
+		b.queues[name] = q
+
+		go q.run(b.done)
+	}
+
+	return q
+}
+
+// closed checks if the broker is closed.
+func (b *Broker) closed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops delivering messages and closes channels of all consumers.
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// queue keeps messages till they are taken by consumers,
+// consumers of one queue compete for its messages.
+type queue struct {
+	broker *Broker
+	name   string
+	// ttl drops messages which aren't taken in time, zero keeps them.
+	ttl      time.Duration
+	mu       sync.Mutex
+	pending  []*delivery
+	ready    chan struct{}
+	messages chan brokers.Delivery
+}
+
+// push adds the message to the end of the queue, it never blocks.
+func (q *queue) push(body []byte, failure brokers.Failure) {
+	msg := &delivery{body: body, failure: failure, queue: q}
+
+	q.mu.Lock()
+	if q.ttl > 0 {
+		msg.expires = time.Now().Add(q.ttl)
+	}
+	q.pending = append(q.pending, msg)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// run hands pending messages to consumers till the broker is closed.
+func (q *queue) run(done <-chan struct{}) {
+	defer close(q.messages)
+
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+
+			select {
+			case <-q.ready:
+				continue
+			case <-done:
+				return
+			}
+		}
+		msg := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		if !q.hand(msg, done) {
+			return
+		}
+	}
+}
+
+// hand waits till the message is taken by a consumer or expires,
+// it returns false if the broker is closed.
+func (q *queue) hand(msg *delivery, done <-chan struct{}) bool {
+	var expired <-chan time.Time
+	if !msg.expires.IsZero() {
+		timer := time.NewTimer(time.Until(msg.expires))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case q.messages <- msg:
+	case <-expired:
+	case <-done:
+		return false
+	}
+
+	return true
+}
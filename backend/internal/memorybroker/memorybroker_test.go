@@ -0,0 +1,152 @@
+package memorybroker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/handlers/slogdiscard"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/memorybroker"
+)
+
+func receive(t *testing.T, consumer brokers.Consumer) (brokers.Delivery, messages.ExpressionMessage) {
+	t.Helper()
+
+	select {
+	case delivery, ok := <-consumer.GetMessages():
+		if !ok {
+			t.Fatalf("channel of messages is closed")
+		}
+		var msg messages.ExpressionMessage
+		if err := json.Unmarshal(delivery.Body(), &msg); err != nil {
+			t.Fatalf("can't decode message: %v", err)
+		}
+		return delivery, msg
+	case <-time.After(time.Second):
+		t.Fatalf("no message in a second")
+	}
+
+	return nil, messages.ExpressionMessage{}
+}
+
+func TestBroker(t *testing.T) {
+	broker := memorybroker.New(slogdiscard.NewDiscardLogger(), 1, "dead letters")
+	defer broker.Close()
+
+	var producer brokers.Producer = broker.NewProducer("tasks")
+	var consumer brokers.Consumer = broker.NewConsumer("tasks")
+	var otherConsumer brokers.Consumer = broker.NewConsumer("results")
+	var deadLetters brokers.Consumer = broker.NewConsumer("dead letters")
+
+	for _, id := range []int32{1, 2} {
+		if err := producer.PublishExpressionMessage(&messages.ExpressionMessage{ExpressionID: id}); err != nil {
+			t.Fatalf("PublishExpressionMessage returned error: %v", err)
+		}
+	}
+
+	delivery, msg := receive(t, consumer)
+	if msg.ExpressionID != 1 {
+		t.Errorf("ExpressionID = %d; want 1", msg.ExpressionID)
+	}
+	if err := delivery.Ack(); err != nil {
+		t.Errorf("Ack returned error: %v", err)
+	}
+	if err := delivery.Ack(); !errors.Is(err, memorybroker.ErrAlreadyAcknowledged) {
+		t.Errorf("second Ack returned %v; want ErrAlreadyAcknowledged", err)
+	}
+
+	// The rejected message is delivered again after the others.
+	delivery, msg = receive(t, consumer)
+	if msg.ExpressionID != 2 {
+		t.Errorf("ExpressionID = %d; want 2", msg.ExpressionID)
+	}
+	if err := delivery.Nack(true); err != nil {
+		t.Errorf("Nack returned error: %v", err)
+	}
+	delivery, msg = receive(t, consumer)
+	if msg.ExpressionID != 2 {
+		t.Errorf("ExpressionID of requeued message = %d; want 2", msg.ExpressionID)
+	}
+	if err := delivery.Nack(false); err != nil {
+		t.Errorf("Nack returned error: %v", err)
+	}
+
+	// The failed message is retried once and then dead-lettered.
+	if err := producer.PublishExpressionMessage(&messages.ExpressionMessage{ExpressionID: 3}); err != nil {
+		t.Fatalf("PublishExpressionMessage returned error: %v", err)
+	}
+	delivery, _ = receive(t, consumer)
+	if err := delivery.Fail("bad token"); err != nil {
+		t.Errorf("Fail returned error: %v", err)
+	}
+	delivery, msg = receive(t, consumer)
+	if msg.ExpressionID != 3 || delivery.Failure().Retries != 1 {
+		t.Errorf("retried message = %d with failure %+v; want 3 with 1 retry", msg.ExpressionID, delivery.Failure())
+	}
+	if err := delivery.Fail("bad token"); err != nil {
+		t.Errorf("Fail returned error: %v", err)
+	}
+	delivery, msg = receive(t, deadLetters)
+	want := brokers.Failure{Queue: "tasks", Reason: "bad token", Retries: 2}
+	if msg.ExpressionID != 3 || delivery.Failure() != want {
+		t.Errorf("dead letter = %d with failure %+v; want 3 with %+v", msg.ExpressionID, delivery.Failure(), want)
+	}
+	if err := delivery.Ack(); err != nil {
+		t.Errorf("Ack returned error: %v", err)
+	}
+
+	select {
+	case delivery := <-consumer.GetMessages():
+		t.Errorf("unexpected message %s", delivery.Body())
+	case delivery := <-otherConsumer.GetMessages():
+		t.Errorf("message of other queue %s", delivery.Body())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	broker.Close()
+
+	if err := producer.PublishExpressionMessage(&messages.ExpressionMessage{}); !errors.Is(err, memorybroker.ErrClosed) {
+		t.Errorf("PublishExpressionMessage after Close returned %v; want ErrClosed", err)
+	}
+	if _, ok := <-consumer.GetMessages(); ok {
+		t.Errorf("channel of messages isn't closed after Close")
+	}
+}
+
+func TestBrokerRouting(t *testing.T) {
+	broker := memorybroker.New(slogdiscard.NewDiscardLogger(), 1, "dead letters")
+	defer broker.Close()
+
+	// Every queue which is bound with the routing key gets the message.
+	first := broker.NewConsumer(broker.Declare(brokers.Queue{RoutingKeys: []string{"control.kill"}, Exclusive: true}))
+	second := broker.NewConsumer(broker.Declare(brokers.Queue{RoutingKeys: []string{"control.kill"}, Exclusive: true}))
+
+	if err := broker.NewProducer("control.kill").PublishExpressionMessage(&messages.ExpressionMessage{Kill: true}); err != nil {
+		t.Fatalf("PublishExpressionMessage returned error: %v", err)
+	}
+	for _, consumer := range []brokers.Consumer{first, second} {
+		if _, msg := receive(t, consumer); !msg.Kill {
+			t.Errorf("Kill = false; want true")
+		}
+	}
+
+	// The message which isn't taken in time is dropped.
+	broker.Declare(brokers.Queue{Name: "heartbeats", RoutingKeys: []string{"ping"}, MessageTTL: 10 * time.Millisecond})
+	heartbeats := broker.NewConsumer("heartbeats")
+
+	producer := broker.NewProducer("ping")
+	if err := producer.PublishExpressionMessage(&messages.ExpressionMessage{AgentID: 1}); err != nil {
+		t.Fatalf("PublishExpressionMessage returned error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := producer.PublishExpressionMessage(&messages.ExpressionMessage{AgentID: 2}); err != nil {
+		t.Fatalf("PublishExpressionMessage returned error: %v", err)
+	}
+
+	if _, msg := receive(t, heartbeats); msg.AgentID != 2 {
+		t.Errorf("AgentID = %d; want 2", msg.AgentID)
+	}
+}
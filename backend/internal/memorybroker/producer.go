@@ -0,0 +1,56 @@
+package memorybroker
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
+)
+
+type Producer struct {
+	log        *slog.Logger
+	broker     *Broker
+	routingKey string
+}
+
+// NewProducer creates new Producer which publishes messages with the routing key.
+func (b *Broker) NewProducer(routingKey string) *Producer {
+	return &Producer{
+		log:        b.log,
+		broker:     b,
+		routingKey: routingKey,
+	}
+}
+
+// PublishExpressionMessage publishes messages to queues of the routing key.
+func (p *Producer) PublishExpressionMessage(msg *messages.ExpressionMessage) error {
+	if p.broker.closed() {
+		return ErrClosed
+	}
+
+	jsonData, err := json.Marshal(msg)
+	if err != nil {
+		return errors.New("failed to encode message to JSON")
+	}
+
+	for _, q := range p.broker.route(p.routingKey) {
+		q.push(jsonData, brokers.Failure{})
+	}
+	p.log.Info("publishing message", slog.String("routing key", p.routingKey))
+
+	return nil
+}
+
+// Reconnect returns the same Producer, there is no connection to restore.
+func (p *Producer) Reconnect() (brokers.Producer, error) {
+	if p.broker.closed() {
+		return nil, ErrClosed
+	}
+
+	return p, nil
+}
+
+// Close does nothing, queues are closed with the Broker.
+func (p *Producer) Close() {}
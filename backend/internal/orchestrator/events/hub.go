@@ -0,0 +1,84 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
+)
+
+// Types of events.
+const (
+	TypeExpression = "expression"
+	TypeAgent      = "agent"
+)
+
+// subscriberBuffer is the number of events which can wait for a slow subscriber,
+// the next events are dropped until it reads them.
+const subscriberBuffer = 64
+
+// Event is the change of the expression or the agent.
+type Event struct {
+	Type string
+	// UserID is the owner of the expression, events with zero UserID are sent to every user.
+	UserID int32
+	Data   interface{}
+}
+
+// ExpressionEvent is the state of the expression with the fraction of its computed nodes.
+type ExpressionEvent struct {
+	postgres.ExpressionTransformed
+	Progress float64 `json:"progress"`
+}
+
+// Hub sends events to subscribers, e.g. browser tabs of the user.
+// It is safe to use from many goroutines.
+type Hub struct {
+	mu          *sync.Mutex
+	subscribers map[chan Event]int32
+}
+
+// NewHub creates new Hub.
+func NewHub() *Hub {
+	return &Hub{
+		mu:          &sync.Mutex{},
+		subscribers: make(map[chan Event]int32),
+	}
+}
+
+// Subscribe returns the channel with events for the user
+// and the function which must be called to unsubscribe.
+func (h *Hub) Subscribe(userID int32) (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subscribers[events] = userID
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[events]; ok {
+			delete(h.subscribers, events)
+			close(events)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// Publish sends the event to subscribers of its user without blocking.
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for events, userID := range h.subscribers {
+		if event.UserID != 0 && event.UserID != userID {
+			continue
+		}
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
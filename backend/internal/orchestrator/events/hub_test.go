@@ -0,0 +1,56 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/events"
+)
+
+func TestHub(t *testing.T) {
+	hub := events.NewHub()
+
+	first, unsubscribeFirst := hub.Subscribe(1)
+	second, unsubscribeSecond := hub.Subscribe(1)
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeSecond()
+	defer unsubscribeOther()
+
+	hub.Publish(events.Event{Type: events.TypeExpression, UserID: 1})
+	hub.Publish(events.Event{Type: events.TypeAgent})
+
+	for _, tc := range []struct {
+		name   string
+		events <-chan events.Event
+		want   []string
+	}{
+		{name: "First tab of the user", events: first, want: []string{events.TypeExpression, events.TypeAgent}},
+		{name: "Second tab of the user", events: second, want: []string{events.TypeExpression, events.TypeAgent}},
+		{name: "Other user", events: other, want: []string{events.TypeAgent}},
+	} {
+		if len(tc.events) != len(tc.want) {
+			t.Fatalf("%s got %d events; want %d", tc.name, len(tc.events), len(tc.want))
+		}
+		for _, want := range tc.want {
+			if got := <-tc.events; got.Type != want {
+				t.Errorf("%s got event %s; want %s", tc.name, got.Type, want)
+			}
+		}
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	hub.Publish(events.Event{Type: events.TypeExpression, UserID: 1})
+	if _, ok := <-first; ok {
+		t.Errorf("event is sent after unsubscribe")
+	}
+}
+
+func TestHubDoesNotBlock(t *testing.T) {
+	hub := events.NewHub()
+	_, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	for i := 0; i < 1000; i++ {
+		hub.Publish(events.Event{Type: events.TypeExpression, UserID: 1})
+	}
+}
@@ -4,28 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/events"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
-
-	"github.com/streadway/amqp"
 )
 
+// ErrNodeIsNotReady is returned when agents send the result of the node
+// that isn't waiting for it, e.g. the node was already computed.
+var ErrNodeIsNotReady = errors.New("node is not ready to be computed")
+
 type Orchestrator struct {
 	log                  *slog.Logger
 	dbConfig             *storage.Storage
 	InactiveTimeForAgent int32
 	mu                   *sync.Mutex
 	kill                 context.CancelFunc
+	Events               *events.Hub
 }
 
 // NewOrchestrator creates new Orchestrator.
@@ -42,32 +49,247 @@ func NewOrchestrator(
 		InactiveTimeForAgent: inactiveTimeForAgent,
 		mu:                   &sync.Mutex{},
 		kill:                 kill,
+		Events:               events.NewHub(),
 	}, nil
 }
 
-// AddTask publish message to agents.
+// AddTask publishes nodes of the expression that are ready to be computed to agents.
+// If the expression has no nodes yet, they are built from its parse data.
 func (o *Orchestrator) AddTask(
+	ctx context.Context,
 	expressionMessage messages.ExpressionMessage,
 	producer brokers.Producer,
-) {
+) error {
 	const fn = "orchestrator.AddTask"
 
 	o.log.Info("orchestrator ready to publish message to queue")
 
-	tokens := parser.GetTokens(o.log, expressionMessage.Expression)
-	for _, token := range tokens {
-		err := producer.PublishExpressionMessage(&messages.ExpressionMessage{
-			ExpressionID: expressionMessage.ExpressionID,
-			Token:        token,
-			Expression:   expressionMessage.Expression,
-			UserID:       expressionMessage.UserID,
-		})
+	nodes, err := o.dbConfig.Queries.GetExpressionNodes(ctx, expressionMessage.ExpressionID)
+	if err != nil {
+		return fmt.Errorf("can't get expression nodes: %v, fn: %s", err, fn)
+	}
+
+	if len(nodes) == 0 {
+		tree, err := parser.BuildTree(expressionMessage.Expression)
+		if err != nil {
+			return fmt.Errorf("can't build expression tree: %v, fn: %s", err, fn)
+		}
+
+		err = tree.Bind(expressionMessage.Bindings)
+		if err != nil {
+			return fmt.Errorf("can't bind variables: %v, fn: %s", err, fn)
+		}
+
+		nodes, err = o.CreateExpressionNodes(ctx, expressionMessage.ExpressionID, tree)
+		if err != nil {
+			return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
+		}
+	}
+
+	for _, node := range nodes {
+		if !node.ParentID.Valid && node.Status == postgres.NodeStatusDone {
+			// The whole expression is already computed.
+			return o.UpdateExpressionToReady(ctx, node.Value, expressionMessage.ExpressionID)
+		}
+	}
+
+	for _, node := range nodes {
+		if node.Status != postgres.NodeStatusReady {
+			continue
+		}
+
+		err := o.publishNode(ctx, node, expressionMessage, producer)
 		if err != nil {
 			o.log.Error("can't publish token to queue", sl.Err(err), slog.String("fn", fn))
-			// TODO: think about it. Should I kill orchestrator?
-			o.kill()
+
+			return err
 		}
 	}
+
+	o.NotifyExpression(ctx, expressionMessage.ExpressionID)
+
+	return nil
+}
+
+// AddTasks publishes nodes of many saved expressions to agents,
+// the expressions which can't be published don't stop the others.
+func (o *Orchestrator) AddTasks(
+	ctx context.Context,
+	expressions []postgres.Expression,
+	producer brokers.Producer,
+) error {
+	const fn = "orchestrator.AddTasks"
+
+	errs := make([]error, 0)
+	for _, expression := range expressions {
+		msgToQueue, err := ExpressionToMessage(expression)
+		if err == nil {
+			err = o.AddTask(ctx, msgToQueue, producer)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expression %d: %v", expression.ExpressionID, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("can't add tasks: %v, fn: %s", errors.Join(errs...), fn)
+	}
+
+	return nil
+}
+
+// ExpressionToMessage makes the message to compute the expression from the database.
+func ExpressionToMessage(expr postgres.Expression) (messages.ExpressionMessage, error) {
+	const fn = "orchestrator.ExpressionToMessage"
+
+	bindings := make(map[string]string)
+	if len(expr.Bindings) > 0 {
+		values := make(map[string]json.Number)
+		err := json.Unmarshal(expr.Bindings, &values)
+		if err != nil {
+			return messages.ExpressionMessage{}, fmt.Errorf("can't parse bindings: %v, fn: %s", err, fn)
+		}
+		for name, value := range values {
+			bindings[name] = value.String()
+		}
+	}
+
+	return messages.ExpressionMessage{
+		ExpressionID: expr.ExpressionID,
+		Expression:   expr.ParseData,
+		Mode:         string(expr.Mode),
+		Bindings:     bindings,
+		UserID:       expr.UserID,
+	}, nil
+}
+
+// CreateExpressionNodes saves the expression tree to the database as a DAG of nodes.
+// Numbers are saved as computed nodes, operators with computed operands are ready to be computed.
+func (o *Orchestrator) CreateExpressionNodes(
+	ctx context.Context,
+	exprID int32,
+	tree *parser.Node,
+) ([]postgres.ExpressionNode, error) {
+	const fn = "orchestrator.CreateExpressionNodes"
+
+	log := o.log.With(
+		slog.String("fn", fn),
+	)
+
+	tx, err := o.dbConfig.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	qtx := o.dbConfig.Queries.WithTx(tx)
+
+	parents := make(map[int32]postgres.ExpressionNode)
+	nodes := make([]postgres.ExpressionNode, 0)
+
+	for _, node := range tree.Nodes() {
+		for ind, operand := range node.Operands {
+			parents[operand.ID] = postgres.ExpressionNode{
+				NodeID:       node.ID,
+				OperandIndex: int32(ind),
+			}
+		}
+
+		parent, hasParent := parents[node.ID]
+
+		exprNode := postgres.ExpressionNode{
+			ExpressionID: exprID,
+			NodeID:       node.ID,
+			ParentID:     sql.NullInt32{Int32: parent.NodeID, Valid: hasParent},
+			OperandIndex: parent.OperandIndex,
+			Operator:     node.Operator,
+			Value:        node.Value,
+			Status:       nodeStatus(node),
+		}
+
+		err := qtx.CreateExpressionNode(ctx, postgres.CreateExpressionNodeParams(exprNode))
+		if err != nil {
+			log.Error("can't create expression node", slog.Int("node ID", int(node.ID)), sl.Err(err))
+			errRollback := tx.Rollback()
+			if errRollback != nil {
+				log.Error("can't rollback transaction")
+
+				return nil, errRollback
+			}
+			return nil, err
+		}
+
+		nodes = append(nodes, exprNode)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("can't commit transaction", sl.Err(err))
+
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// nodeStatus returns the initial status of the node.
+func nodeStatus(node *parser.Node) postgres.NodeStatus {
+	if node.IsLeaf() {
+		return postgres.NodeStatusDone
+	}
+
+	for _, operand := range node.Operands {
+		if !operand.IsLeaf() {
+			return postgres.NodeStatusWaiting
+		}
+	}
+
+	return postgres.NodeStatusReady
+}
+
+// publishNode publishes the token of the node that is ready to be computed to agents.
+func (o *Orchestrator) publishNode(
+	ctx context.Context,
+	node postgres.ExpressionNode,
+	expressionMessage messages.ExpressionMessage,
+	producer brokers.Producer,
+) error {
+	const fn = "orchestrator.publishNode"
+
+	operands, err := o.dbConfig.Queries.GetExpressionNodeOperands(
+		ctx,
+		postgres.GetExpressionNodeOperandsParams{
+			ExpressionID: node.ExpressionID,
+			ParentID:     sql.NullInt32{Int32: node.NodeID, Valid: true},
+		})
+	if err != nil {
+		return fmt.Errorf("can't get operands of the node: %v, fn: %s", err, fn)
+	}
+
+	token := parser.Token(node.Operator, operands)
+
+	err = producer.PublishExpressionMessage(&messages.ExpressionMessage{
+		ExpressionID: node.ExpressionID,
+		NodeID:       node.NodeID,
+		Token:        token,
+		Expression:   expressionMessage.Expression,
+		Mode:         expressionMessage.Mode,
+		UserID:       expressionMessage.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("can't publish node to queue: %v, fn: %s", err, fn)
+	}
+
+	// The node which is published again keeps its step with the new dispatch time.
+	err = o.dbConfig.Queries.UpsertExpressionStep(ctx, postgres.UpsertExpressionStepParams{
+		ExpressionID: node.ExpressionID,
+		NodeID:       node.NodeID,
+		Token:        token,
+		DispatchedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("can't save step of the expression: %v, fn: %s", err, fn)
+	}
+
+	return nil
 }
 
 // ReloadComputingExpressions add not completed expressions again to queue.
@@ -83,12 +305,14 @@ func (o *Orchestrator) ReloadComputingExpressions(
 	}
 
 	for _, expr := range expressions {
-		msgToQueue := messages.ExpressionMessage{
-			ExpressionID: expr.ExpressionID,
-			Expression:   expr.ParseData,
-			UserID:       expr.UserID,
+		msgToQueue, err := ExpressionToMessage(expr)
+		if err != nil {
+			return fmt.Errorf("orhestrator Error: %v, fn: %s", err, fn)
+		}
+		err = o.AddTask(ctx, msgToQueue, producer)
+		if err != nil {
+			return fmt.Errorf("orhestrator Error: %v, fn: %s", err, fn)
 		}
-		o.AddTask(msgToQueue, producer)
 	}
 
 	return nil
@@ -161,6 +385,10 @@ func (o *Orchestrator) CheckPing(ctx context.Context, producer brokers.Producer)
 		return err
 	}
 
+	for _, agentID := range agentIDs {
+		o.NotifyAgent(ctx, agentID)
+	}
+
 	expressions, err := o.dbConfig.Queries.GetTerminatedExpressions(ctx)
 	if err != nil {
 		log.Error("can't get expressions", sl.Err(err))
@@ -169,12 +397,46 @@ func (o *Orchestrator) CheckPing(ctx context.Context, producer brokers.Producer)
 	}
 
 	for _, expr := range expressions {
-		msgToQueue := messages.ExpressionMessage{
-			ExpressionID: expr.ExpressionID,
-			Expression:   expr.ParseData,
-			UserID:       expr.UserID,
+		msgToQueue, err := ExpressionToMessage(expr)
+		if err != nil {
+			log.Error("can't add task", slog.Int("expression ID", int(expr.ExpressionID)), sl.Err(err))
+			continue
+		}
+		err = o.AddTask(ctx, msgToQueue, producer)
+		if err != nil {
+			log.Error("can't add task", slog.Int("expression ID", int(expr.ExpressionID)), sl.Err(err))
 		}
-		o.AddTask(msgToQueue, producer)
+	}
+
+	return nil
+}
+
+// CheckDeadlines fails expressions which aren't computed before their deadline.
+// Agents abort computations of these expressions on the next ping.
+func (o *Orchestrator) CheckDeadlines(ctx context.Context) error {
+	const fn = "orchestrator.CheckDeadlines"
+
+	exprIDs, err := o.dbConfig.Queries.MakeExpressionsTimedOut(ctx, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("can't make expressions timed out: %v, fn: %s", err, fn)
+	}
+
+	for _, exprID := range exprIDs {
+		o.log.Info("expression timed out", slog.String("fn", fn), slog.Int("expression ID", int(exprID)))
+		o.NotifyExpression(ctx, exprID)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes idempotency keys after their TTL,
+// so they can be used again.
+func (o *Orchestrator) DeleteExpiredIdempotencyKeys(ctx context.Context) error {
+	const fn = "orchestrator.DeleteExpiredIdempotencyKeys"
+
+	err := o.dbConfig.Queries.DeleteExpiredIdempotencyKeys(ctx, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("can't delete expired idempotency keys: %v, fn: %s", err, fn)
 	}
 
 	return nil
@@ -234,12 +496,15 @@ func (o *Orchestrator) FindForgottenExpressions(ctx context.Context, producer br
 	}
 
 	for _, expr := range expressions {
-		msgToQueue := messages.ExpressionMessage{
-			ExpressionID: expr.ExpressionID,
-			Expression:   expr.ParseData,
-			UserID:       expr.UserID,
+		msgToQueue, err := ExpressionToMessage(expr)
+		if err != nil {
+			log.Error("can't add task", slog.Int("expression ID", int(expr.ExpressionID)), sl.Err(err))
+			continue
+		}
+		err = o.AddTask(ctx, msgToQueue, producer)
+		if err != nil {
+			log.Error("can't add task", slog.Int("expression ID", int(expr.ExpressionID)), sl.Err(err))
 		}
-		o.AddTask(msgToQueue, producer)
 	}
 
 	return nil
@@ -259,10 +524,14 @@ func (o *Orchestrator) HandlePing(ctx context.Context, agentID int32) error {
 		return fmt.Errorf("can't update last ping: %v, fn: %s", err, fn)
 	}
 
+	o.NotifyAgent(ctx, agentID)
+
 	return nil
 }
 
-// HandleExpression makes expressions ready or publishes it again to queue.
+// HandleExpression saves the result of the node and publishes its parent
+// if the parent became ready to be computed, or makes the expression ready
+// if the node is the root of the expression.
 func (o *Orchestrator) HandleExpression(
 	ctx context.Context,
 	exprMsg messages.ExpressionMessage,
@@ -270,30 +539,76 @@ func (o *Orchestrator) HandleExpression(
 ) error {
 	const fn = "orchestrator.HandleExpression"
 
-	newResultAndToken, err := o.UpdateExpressionFromAgents(ctx, exprMsg)
+	expression, err := o.dbConfig.Queries.GetExpressionByID(ctx, exprMsg.ExpressionID)
 	if err != nil {
-		return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
+		return fmt.Errorf("can't get expression by id: %v, fn: %s", err, fn)
+	}
+	if isFinished(expression.Status) {
+		o.log.Info(
+			"skip result of the finished expression",
+			slog.String("fn", fn),
+			slog.Int("expression ID", int(exprMsg.ExpressionID)),
+			slog.String("status", string(expression.Status)),
+		)
+
+		return nil
 	}
 
-	result, err := strconv.Atoi(newResultAndToken.Result)
+	overflow := exprMsg.Mode != string(postgres.EvaluationModeArbitrary) &&
+		(exprMsg.Overflow || isOverflow(exprMsg.Result))
 
-	if err == nil &&
-		parser.IsNumber(newResultAndToken.Result) ||
-		(newResultAndToken.Result[0] == '-' && parser.IsNumber(newResultAndToken.Result[1:])) {
-		err := o.UpdateExpressionToReady(ctx, result, exprMsg.ExpressionID)
+	// The step of the computed node is saved with the node in UpdateExpressionFromAgents.
+	if exprMsg.Error != "" || overflow {
+		err := completeExpressionStep(ctx, o.dbConfig.Queries, exprMsg)
+		if err != nil {
+			return fmt.Errorf("can't save step of the expression: %v, fn: %s", err, fn)
+		}
+	}
+
+	if exprMsg.Error != "" {
+		err := o.UpdateExpressionToError(ctx, exprMsg.Error, exprMsg.ExpressionID)
 		if err != nil {
 			return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
 		}
 
 		return nil
 	}
-	if newResultAndToken.Token != "" {
-		err := producer.PublishExpressionMessage(&messages.ExpressionMessage{
-			ExpressionID: exprMsg.ExpressionID,
-			Token:        newResultAndToken.Token,
-			Expression:   newResultAndToken.Result,
-			UserID:       exprMsg.UserID,
-		})
+
+	if overflow {
+		err := o.UpdateExpressionToOverflow(ctx, exprMsg.ExpressionID)
+		if err != nil {
+			return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
+		}
+
+		return nil
+	}
+
+	node, parent, err := o.UpdateExpressionFromAgents(ctx, exprMsg)
+	if errors.Is(err, ErrNodeIsNotReady) {
+		o.log.Info(
+			"skip result of the node that was already computed",
+			slog.String("fn", fn),
+			slog.Int("expression ID", int(exprMsg.ExpressionID)),
+			slog.Int("node ID", int(exprMsg.NodeID)),
+		)
+
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
+	}
+
+	if !node.ParentID.Valid {
+		err := o.UpdateExpressionToReady(ctx, exprMsg.Result, exprMsg.ExpressionID)
+		if err != nil {
+			return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
+		}
+
+		return nil
+	}
+
+	if parent != nil {
+		err := o.publishNode(ctx, *parent, exprMsg, producer)
 		if err != nil {
 			return fmt.Errorf("orchestrator error: %v, fn: %s", err, fn)
 		}
@@ -302,50 +617,200 @@ func (o *Orchestrator) HandleExpression(
 	return nil
 }
 
-// UpdateExpressionFromAgents parses expression with new token and updates it in the database.
+// UpdateExpressionFromAgents saves the result of the node computed by agents
+// and makes its parent ready if all operands of the parent are computed.
+// Then parse data of the expression is updated with the new result.
+// Returns the computed node and its parent if the parent became ready.
 func (o *Orchestrator) UpdateExpressionFromAgents(
 	ctx context.Context,
 	exprMsg messages.ExpressionMessage,
-) (messages.ResultAndTokenMessage, error) {
+) (postgres.ExpressionNode, *postgres.ExpressionNode, error) {
 	const fn = "orchestrator.UpdateExpressionFromAgents"
 
-	expression, err := o.dbConfig.Queries.GetExpressionByID(
-		ctx,
-		exprMsg.ExpressionID,
+	log := o.log.With(
+		slog.String("fn", fn),
 	)
+
+	node, err := o.dbConfig.Queries.GetExpressionNodeByID(
+		ctx,
+		postgres.GetExpressionNodeByIDParams{
+			ExpressionID: exprMsg.ExpressionID,
+			NodeID:       exprMsg.NodeID,
+		})
 	if err != nil {
-		return messages.ResultAndTokenMessage{},
-			fmt.Errorf("can't get expression by id: %v, fn: %s", err, fn)
+		return postgres.ExpressionNode{}, nil,
+			fmt.Errorf("can't get expression node by id: %v, fn: %s", err, fn)
 	}
 
-	resAndTokenMsg, err := parser.InsertResultToToken(
-		expression.ParseData,
-		exprMsg.Token,
-		exprMsg.Result,
-	)
+	tx, err := o.dbConfig.DB.Begin()
+	if err != nil {
+		return postgres.ExpressionNode{}, nil, err
+	}
+
+	qtx := o.dbConfig.Queries.WithTx(tx)
+
+	rollback := func(err error) (postgres.ExpressionNode, *postgres.ExpressionNode, error) {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			log.Error("can't rollback transaction")
+
+			return postgres.ExpressionNode{}, nil, errRollback
+		}
+		return postgres.ExpressionNode{}, nil, err
+	}
+
+	if node.ParentID.Valid {
+		// Lock the parent, so operands that are computed at the same time
+		// can't miss each other and leave the parent waiting forever.
+		err := qtx.LockExpressionNode(ctx, postgres.LockExpressionNodeParams{
+			ExpressionID: node.ExpressionID,
+			NodeID:       node.ParentID.Int32,
+		})
+		if err != nil {
+			log.Error("can't lock parent node", sl.Err(err))
+
+			return rollback(err)
+		}
+	}
+
+	rows, err := qtx.MakeExpressionNodeDone(ctx, postgres.MakeExpressionNodeDoneParams{
+		Value:        exprMsg.Result,
+		ExpressionID: node.ExpressionID,
+		NodeID:       node.NodeID,
+	})
 	if err != nil {
-		return messages.ResultAndTokenMessage{},
-			fmt.Errorf("can't insert tokens to expression: %v, fn: %s", err, fn)
+		log.Error("can't make node done", sl.Err(err))
+
+		return rollback(err)
+	}
+	if rows == 0 {
+		return rollback(ErrNodeIsNotReady)
+	}
+
+	// The step is saved only with the result of the node,
+	// so the duplicate of the result doesn't overwrite it.
+	err = completeExpressionStep(ctx, qtx, exprMsg)
+	if err != nil {
+		log.Error("can't save step of the expression", sl.Err(err))
+
+		return rollback(err)
+	}
+
+	var parent *postgres.ExpressionNode
+
+	if node.ParentID.Valid {
+		rows, err := qtx.MakeExpressionNodeReady(ctx, postgres.MakeExpressionNodeReadyParams{
+			ExpressionID: node.ExpressionID,
+			NodeID:       node.ParentID.Int32,
+		})
+		if err != nil {
+			log.Error("can't make parent node ready", sl.Err(err))
+
+			return rollback(err)
+		}
+		if rows != 0 {
+			parentNode, err := qtx.GetExpressionNodeByID(ctx, postgres.GetExpressionNodeByIDParams{
+				ExpressionID: node.ExpressionID,
+				NodeID:       node.ParentID.Int32,
+			})
+			if err != nil {
+				log.Error("can't get parent node", sl.Err(err))
+
+				return rollback(err)
+			}
+			parent = &parentNode
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("can't commit transaction", sl.Err(err))
+
+		return postgres.ExpressionNode{}, nil, err
+	}
+
+	nodes, err := o.dbConfig.Queries.GetExpressionNodes(ctx, node.ExpressionID)
+	if err != nil {
+		return postgres.ExpressionNode{}, nil,
+			fmt.Errorf("can't get expression nodes: %v, fn: %s", err, fn)
 	}
 
 	err = o.dbConfig.Queries.UpdateExpressionParseData(
 		ctx,
 		postgres.UpdateExpressionParseDataParams{
-			ExpressionID: exprMsg.ExpressionID,
-			ParseData:    resAndTokenMsg.Result,
+			ExpressionID: node.ExpressionID,
+			ParseData:    nodesToTree(nodes).Postfix(),
 		})
 	if err != nil {
-		return messages.ResultAndTokenMessage{},
+		return postgres.ExpressionNode{}, nil,
 			fmt.Errorf("can't update expression data: %v, fn: %s", err, fn)
 	}
 
-	return resAndTokenMsg, nil
+	o.NotifyExpression(ctx, node.ExpressionID)
+
+	return node, parent, nil
+}
+
+// completeExpressionStep saves the result or the error of the node computed by agents in the steps of the expression.
+func completeExpressionStep(
+	ctx context.Context,
+	queries *postgres.Queries,
+	exprMsg messages.ExpressionMessage,
+) error {
+	return queries.CompleteExpressionStep(ctx, postgres.CompleteExpressionStepParams{
+		AgentID:      sql.NullInt32{Int32: exprMsg.AgentID, Valid: exprMsg.AgentID != 0},
+		Result:       exprMsg.Result,
+		ErrorMessage: exprMsg.Error,
+		ComputedAt:   sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		ExpressionID: exprMsg.ExpressionID,
+		NodeID:       exprMsg.NodeID,
+	})
+}
+
+// nodesToTree builds the expression tree from the nodes saved in the database.
+// Computed nodes become leaves with their results.
+func nodesToTree(nodes []postgres.ExpressionNode) *parser.Node {
+	treeNodes := make(map[int32]*parser.Node, len(nodes))
+	for _, node := range nodes {
+		treeNodes[node.NodeID] = &parser.Node{
+			ID:       node.NodeID,
+			Value:    node.Value,
+			Operator: node.Operator,
+		}
+		if node.Status == postgres.NodeStatusDone {
+			treeNodes[node.NodeID].Operator = ""
+		}
+	}
+
+	var root *parser.Node
+
+	operands := make(map[int32][]postgres.ExpressionNode)
+	for _, node := range nodes {
+		if !node.ParentID.Valid {
+			root = treeNodes[node.NodeID]
+			continue
+		}
+		operands[node.ParentID.Int32] = append(operands[node.ParentID.Int32], node)
+	}
+
+	for parentID, children := range operands {
+		parent := treeNodes[parentID]
+		if parent.IsLeaf() {
+			continue
+		}
+		parent.Operands = make([]*parser.Node, len(children))
+		for _, child := range children {
+			parent.Operands[child.OperandIndex] = treeNodes[child.NodeID]
+		}
+	}
+
+	return root
 }
 
 // UpdateExpressionToReady updates expression to ready.
 func (o *Orchestrator) UpdateExpressionToReady(
 	ctx context.Context,
-	result int,
+	result string,
 	exprID int32,
 ) error {
 	const fn = "orchestrator.UpdateExpressionToReady"
@@ -354,7 +819,7 @@ func (o *Orchestrator) UpdateExpressionToReady(
 		ctx,
 		postgres.MakeExpressionReadyParams{
 			ParseData:    "",
-			Result:       int32(result),
+			Result:       result,
 			UpdatedAt:    time.Now().UTC(),
 			ExpressionID: exprID,
 		})
@@ -362,15 +827,193 @@ func (o *Orchestrator) UpdateExpressionToReady(
 		return fmt.Errorf("can't make expression ready: %v, fn: %s", err, fn)
 	}
 
+	o.NotifyExpression(ctx, exprID)
+
+	return nil
+}
+
+// UpdateExpressionToOverflow marks expression as failed
+// because its result doesn't fit in the standard evaluation mode.
+// The expression which is already finished, e.g. cancelled, isn't changed.
+func (o *Orchestrator) UpdateExpressionToOverflow(
+	ctx context.Context,
+	exprID int32,
+) error {
+	const fn = "orchestrator.UpdateExpressionToOverflow"
+
+	rows, err := o.dbConfig.Queries.MakeExpressionOverflow(
+		ctx,
+		postgres.MakeExpressionOverflowParams{
+			UpdatedAt:    time.Now().UTC(),
+			ExpressionID: exprID,
+		})
+	if err != nil {
+		return fmt.Errorf("can't make expression overflow: %v, fn: %s", err, fn)
+	}
+	if rows == 0 {
+		o.log.Info("expression is already finished", slog.String("fn", fn), slog.Int("expression ID", int(exprID)))
+		return nil
+	}
+
+	o.NotifyExpression(ctx, exprID)
+
 	return nil
 }
 
-// HandleMessagesFromAgents consumes message from agents.
-// If it is ping handle it with HandlePing method.
-// If it is expression handle it with HandleExpression method.
+// UpdateExpressionToError updates expression to error, so it isn't computed anymore.
+func (o *Orchestrator) UpdateExpressionToError(
+	ctx context.Context,
+	errorMessage string,
+	exprID int32,
+) error {
+	const fn = "orchestrator.UpdateExpressionToError"
+
+	err := o.dbConfig.Queries.MakeExpressionError(
+		ctx,
+		postgres.MakeExpressionErrorParams{
+			UpdatedAt:    time.Now().UTC(),
+			ErrorMessage: errorMessage,
+			ExpressionID: exprID,
+		})
+	if err != nil {
+		return fmt.Errorf("can't make expression error: %v, fn: %s", err, fn)
+	}
+
+	o.NotifyExpression(ctx, exprID)
+
+	return nil
+}
+
+// NotifyExpression sends the current state of the expression and its progress to subscribers of its user.
+func (o *Orchestrator) NotifyExpression(ctx context.Context, exprID int32) {
+	const fn = "orchestrator.NotifyExpression"
+
+	log := o.log.With(
+		slog.String("fn", fn),
+		slog.Int("expression ID", int(exprID)),
+	)
+
+	expression, err := o.dbConfig.Queries.GetExpressionByID(ctx, exprID)
+	if err != nil {
+		log.Error("can't get expression", sl.Err(err))
+		return
+	}
+
+	progress, err := o.dbConfig.Queries.GetExpressionProgress(ctx, exprID)
+	if err != nil {
+		log.Error("can't get progress of expression", sl.Err(err))
+		return
+	}
+
+	event := events.ExpressionEvent{
+		ExpressionTransformed: postgres.DatabaseExpressionToExpression(expression),
+	}
+	switch {
+	case expression.Status == postgres.ExpressionStatusResult:
+		event.Progress = 1
+	case progress.Total != 0:
+		event.Progress = float64(progress.Done) / float64(progress.Total)
+	}
+
+	o.Events.Publish(events.Event{
+		Type:   events.TypeExpression,
+		UserID: expression.UserID,
+		Data:   event,
+	})
+}
+
+// WaitForExpression waits until the expression is finished or the timeout elapses
+// and returns the expression.
+func (o *Orchestrator) WaitForExpression(
+	ctx context.Context,
+	exprID int32,
+	userID int32,
+	timeout time.Duration,
+) (postgres.Expression, error) {
+	const fn = "orchestrator.WaitForExpression"
+
+	// Subscribe before checking the status, so the change between them isn't missed.
+	updates, unsubscribe := o.Events.Subscribe(userID)
+	defer unsubscribe()
+
+	expression, err := o.dbConfig.Queries.GetExpressionByID(ctx, exprID)
+	if err != nil {
+		return postgres.Expression{}, fmt.Errorf("can't get expression by id: %v, fn: %s", err, fn)
+	}
+	if isFinished(expression.Status) {
+		return expression, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-timer.C:
+			break wait
+		case event := <-updates:
+			update, ok := event.Data.(events.ExpressionEvent)
+			if ok && update.ExpressionID == exprID && isFinished(update.Status) {
+				break wait
+			}
+		}
+	}
+
+	expression, err = o.dbConfig.Queries.GetExpressionByID(context.WithoutCancel(ctx), exprID)
+	if err != nil {
+		return postgres.Expression{}, fmt.Errorf("can't get expression by id: %v, fn: %s", err, fn)
+	}
+
+	return expression, nil
+}
+
+// NotifyAgent sends the current state of the agent to all users.
+func (o *Orchestrator) NotifyAgent(ctx context.Context, agentID int32) {
+	const fn = "orchestrator.NotifyAgent"
+
+	agent, err := o.dbConfig.Queries.GetAgentByID(ctx, agentID)
+	if err != nil {
+		o.log.Error("can't get agent", slog.String("fn", fn), slog.Int("agent ID", int(agentID)), sl.Err(err))
+		return
+	}
+
+	o.Events.Publish(events.Event{
+		Type: events.TypeAgent,
+		Data: postgres.DatabaseAgentToAgent(agent),
+	})
+}
+
+// isFinished checks if the expression has the final status and its nodes don't need to be computed.
+func isFinished(status postgres.ExpressionStatus) bool {
+	return status == postgres.ExpressionStatusResult ||
+		status == postgres.ExpressionStatusOverflow ||
+		status == postgres.ExpressionStatusError ||
+		status == postgres.ExpressionStatusCancelled ||
+		status == postgres.ExpressionStatusTimedOut
+}
+
+// maxExactInteger is the greatest integer such that float64 keeps it and all smaller integers exactly.
+const maxExactInteger = 1<<53 - 1
+
+// isOverflow checks if the result of the standard evaluation mode is out of range of float64
+// or out of range of integers that float64 keeps exactly, so its digits may be lost.
+func isOverflow(result string) bool {
+	value, err := strconv.ParseFloat(result, 64)
+	if err != nil {
+		return errors.Is(err, strconv.ErrRange)
+	}
+
+	return math.IsInf(value, 0) || math.IsNaN(value) || math.Abs(value) > maxExactInteger
+}
+
+// HandleMessagesFromAgents consumes results of agents and handles them with HandleExpression method.
+// The message which can't be handled is failed, so it's retried and then dead-lettered.
 func (o *Orchestrator) HandleMessagesFromAgents(
 	ctx context.Context,
-	msgFromAgents amqp.Delivery,
+	msgFromAgents brokers.Delivery,
 	producer brokers.Producer,
 ) error {
 	const fn = "orchestrator.HandleMessagesFromAgents"
@@ -379,32 +1022,101 @@ func (o *Orchestrator) HandleMessagesFromAgents(
 		slog.String("fn", fn),
 	)
 
-	log.Info("orchestrator consumes message from agent", slog.String("msg", string(msgFromAgents.Body)))
+	log.Info("orchestrator consumes message from agent", slog.String("msg", string(msgFromAgents.Body())))
 
-	err := msgFromAgents.Ack(false)
+	var exprMsg messages.ExpressionMessage
+	if err := json.Unmarshal(msgFromAgents.Body(), &exprMsg); err != nil {
+		log.Error("failed to parse JSON", sl.Err(err))
+		return settleMessage(msgFromAgents, fmt.Errorf("failed to parse JSON: %v", err))
+	}
+
+	err := o.HandleExpression(ctx, exprMsg, producer)
 	if err != nil {
-		log.Error("error acknowledging message", sl.Err(err))
-		return err
+		log.Error("orchestrator error", sl.Err(err))
 	}
 
+	return settleMessage(msgFromAgents, err)
+}
+
+// HandleHeartbeat consumes ping of the agent and handles it with HandlePing method.
+// Pings aren't retried, the agent sends the next one soon.
+func (o *Orchestrator) HandleHeartbeat(ctx context.Context, heartbeat brokers.Delivery) error {
+	const fn = "orchestrator.HandleHeartbeat"
+
 	var exprMsg messages.ExpressionMessage
-	if err := json.Unmarshal(msgFromAgents.Body, &exprMsg); err != nil {
-		log.Error("failed to parse JSON", sl.Err(err))
-		return err
+	err := json.Unmarshal(heartbeat.Body(), &exprMsg)
+	if err == nil {
+		err = o.HandlePing(ctx, exprMsg.AgentID)
 	}
-
-	if exprMsg.IsPing {
-		err := o.HandlePing(ctx, exprMsg.AgentID)
-		if err != nil {
-			log.Error("orchestrator error", sl.Err(err))
-			return err
+	if err != nil {
+		nackErr := heartbeat.Nack(false)
+		if nackErr != nil && !errors.Is(nackErr, brokers.ErrStaleDelivery) {
+			return fmt.Errorf("can't nack heartbeat: %v, fn: %s", nackErr, fn)
 		}
+
+		return fmt.Errorf("can't handle heartbeat: %v, fn: %s", err, fn)
+	}
+
+	err = heartbeat.Ack()
+	if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+		return fmt.Errorf("can't acknowledge heartbeat: %v, fn: %s", err, fn)
+	}
+
+	return nil
+}
+
+// settleMessage acknowledges the handled message or fails it with the error of handling.
+func settleMessage(msg brokers.Delivery, handleErr error) error {
+	var err error
+	if handleErr != nil {
+		err = msg.Fail(handleErr.Error())
 	} else {
-		err := o.HandleExpression(ctx, exprMsg, producer)
-		if err != nil {
-			log.Error("", sl.Err(err))
-			return err
+		err = msg.Ack()
+	}
+
+	// The stale message is delivered again, so it's settled then.
+	if errors.Is(err, brokers.ErrStaleDelivery) {
+		return nil
+	}
+
+	return err
+}
+
+// HandleDeadLetter saves the message which failed too many times,
+// so admins can inspect it and requeue or discard it.
+func (o *Orchestrator) HandleDeadLetter(ctx context.Context, deadLetter brokers.Delivery) error {
+	const fn = "orchestrator.HandleDeadLetter"
+
+	failure := deadLetter.Failure()
+
+	_, err := o.dbConfig.Queries.CreateDeadLetter(ctx, postgres.CreateDeadLetterParams{
+		Queue:     failure.Queue,
+		Body:      strings.ToValidUTF8(string(deadLetter.Body()), "\uFFFD"),
+		Reason:    failure.Reason,
+		Retries:   int32(failure.Retries),
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		// The message stays in the dead-letter queue till it's saved.
+		nackErr := deadLetter.Nack(true)
+		if nackErr != nil && !errors.Is(nackErr, brokers.ErrStaleDelivery) {
+			return fmt.Errorf("can't nack dead letter: %v, fn: %s", nackErr, fn)
 		}
+
+		return fmt.Errorf("can't save dead letter: %v, fn: %s", err, fn)
+	}
+
+	o.log.Warn(
+		"message is dead-lettered",
+		slog.String("fn", fn),
+		slog.String("queue", failure.Queue),
+		slog.String("reason", failure.Reason),
+		slog.Int("retries", failure.Retries),
+	)
+
+	err = deadLetter.Ack()
+	if err != nil && !errors.Is(err, brokers.ErrStaleDelivery) {
+		return fmt.Errorf("can't acknowledge dead letter: %v, fn: %s", err, fn)
 	}
 
 	return nil
@@ -6,18 +6,17 @@ import (
 	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/pool"
-	"github.com/streadway/amqp"
 )
 
 type orchestratorTask struct {
 	orchestrator  *Orchestrator
 	ctx           context.Context
-	msgFromAgents amqp.Delivery
+	msgFromAgents brokers.Delivery
 	producer      brokers.Producer
 }
 
 // ExecuteWrapper is a wrapper function to call the Execute method with the necessary arguments.
-func ExecuteWrapper(o *Orchestrator, ctx context.Context, msgFromAgents amqp.Delivery, producer brokers.Producer) pool.PoolTask {
+func ExecuteWrapper(o *Orchestrator, ctx context.Context, msgFromAgents brokers.Delivery, producer brokers.Producer) pool.PoolTask {
 	return &orchestratorTask{o, ctx, msgFromAgents, producer}
 }
 
@@ -26,8 +25,8 @@ func (ot *orchestratorTask) Execute() error {
 	return ot.orchestrator.HandleMessagesFromAgents(ot.ctx, ot.msgFromAgents, ot.producer)
 }
 
-// OnFailure implements the OnFailure method of the PoolTask interface
+// OnFailure implements the OnFailure method of the PoolTask interface.
+// Failed messages are retried and dead-lettered, so the orchestrator keeps working.
 func (ot *orchestratorTask) OnFailure(err error) {
 	ot.orchestrator.log.Error("orchestrator error", sl.Err(err))
-	ot.orchestrator.kill()
 }
@@ -0,0 +1,56 @@
+package parser
+
+import "fmt"
+
+// ErrorCode tells what is wrong with the expression.
+type ErrorCode string
+
+const (
+	CodeEmptyExpression       ErrorCode = "empty_expression"
+	CodeUnbalancedParen       ErrorCode = "unbalanced_paren"
+	CodeUnexpectedToken       ErrorCode = "unexpected_token"
+	CodeUnexpectedEnd         ErrorCode = "unexpected_end"
+	CodeInvalidNumber         ErrorCode = "invalid_number"
+	CodeDivisionByZeroLiteral ErrorCode = "division_by_zero_literal"
+	CodeUnknownFunction       ErrorCode = "unknown_function"
+	CodeWrongArgumentCount    ErrorCode = "wrong_argument_count"
+)
+
+// Hints of what was expected at the position of the error.
+const (
+	ExpectedNumber   = "number"
+	ExpectedVariable = "variable"
+	ExpectedFunction = "function"
+	ExpectedOperator = "operator"
+	ExpectedOpening  = "("
+	ExpectedClosing  = ")"
+	ExpectedComma    = ","
+)
+
+// ParseError is returned when the expression from the user is invalid.
+type ParseError struct {
+	Code ErrorCode `json:"code"`
+	// Message describes the error for humans.
+	Message string `json:"message"`
+	// Offset is the index of the character where the error is found, counted in characters
+	// of the expression, it is equal to the length of the expression if the expression ends too early.
+	Offset int `json:"offset"`
+	// Expected lists what could be at the Offset to make the expression valid.
+	Expected []string `json:"expected,omitempty"`
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid expression: %s at offset %d", e.Message, e.Offset)
+}
+
+func newParseError(code ErrorCode, offset int, message string, expected ...string) *ParseError {
+	return &ParseError{
+		Code:     code,
+		Message:  message,
+		Offset:   offset,
+		Expected: expected,
+	}
+}
+
+// expectedOperand are hints for the position where a number or something like a number is needed.
+var expectedOperand = []string{ExpectedNumber, ExpectedVariable, ExpectedFunction, ExpectedOpening}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// arity is the allowed number of arguments of the function,
+// max equal to 0 means any number of arguments.
+type arity struct {
+	min int
+	max int
+}
+
+// functions are built-in functions which can be called in expressions.
+var functions = map[string]arity{
+	"sqrt": {min: 1, max: 1},
+	"abs":  {min: 1, max: 1},
+	"pow":  {min: 2, max: 2},
+	"min":  {min: 1, max: 0},
+	"max":  {min: 1, max: 0},
+}
+
+// IsFunction checks if name is a built-in function.
+func IsFunction(name string) bool {
+	_, ok := functions[name]
+	return ok
+}
+
+// isValidArity checks if the function can be called with the given number of arguments.
+func isValidArity(name string, args int) bool {
+	fnArity, ok := functions[name]
+	if !ok {
+		return false
+	}
+	return args >= fnArity.min && (fnArity.max == 0 || args <= fnArity.max)
+}
+
+// functionToken returns the function call in postfix record like max:3,
+// the number of arguments is needed because min and max take any number of them.
+func functionToken(name string, args int) string {
+	return fmt.Sprintf("%s:%d", name, args)
+}
+
+// parseFunctionToken parses the token made by functionToken.
+func parseFunctionToken(token string) (name string, args int, ok bool) {
+	name, argsStr, found := strings.Cut(token, ":")
+	if !found || !IsFunction(name) {
+		return "", 0, false
+	}
+	args, err := strconv.Atoi(argsStr)
+	if err != nil || !isValidArity(name, args) {
+		return "", 0, false
+	}
+	return name, args, true
+}
+
+// readName reads the name of a function or a variable which starts at the start index of the expression.
+func readName(expression string, start int) string {
+	end := start
+	for end < len(expression) && isNameChar(rune(expression[end])) {
+		end++
+	}
+	return expression[start:end]
+}
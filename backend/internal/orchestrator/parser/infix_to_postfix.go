@@ -8,22 +8,55 @@ import (
 // InfixToPostfix translates an expression from infix record to postfix.
 func InfixToPostfix(expression string) (string, error) {
 	var output strings.Builder
-	var stack []rune
-	for _, char := range expression {
-		switch char {
-		case '(':
-			stack = append(stack, char)
-		case ')':
+	var stack []string
+	// Number of arguments of the function calls which are not closed yet.
+	var args []int
+	for ind := 0; ind < len(expression); ind++ {
+		char := expression[ind]
+		switch {
+		case char == '(':
+			stack = append(stack, "(")
+		case char == ')':
 			err := popUntilOpeningParenthesis(&stack, &output)
 			if err != nil {
 				return "", err
 			}
-		case '+', '-', '*', '/':
-			popOperatorsWithHigherPrecedence(char, &stack, &output)
-			stack = append(stack, char)
+			if len(stack) > 0 && IsFunction(stack[len(stack)-1]) {
+				if len(args) == 0 {
+					return "", errors.New("invalid expression")
+				}
+				output.WriteRune(' ')
+				output.WriteString(functionToken(stack[len(stack)-1], args[len(args)-1]))
+				stack = stack[:len(stack)-1]
+				args = args[:len(args)-1]
+			}
+		case char == ',':
+			for len(stack) > 0 && stack[len(stack)-1] != "(" {
+				popTopOperator(&stack, &output)
+			}
+			if len(stack) < 2 || !IsFunction(stack[len(stack)-2]) || len(args) == 0 {
+				return "", errors.New("invalid expression")
+			}
+			args[len(args)-1]++
+			output.WriteRune(' ')
+		case isNameStart(rune(char)):
+			name := readName(expression, ind)
+			ind += len(name) - 1
+			if !IsFunction(name) {
+				// Variables are written to the output like numbers.
+				output.WriteString(name)
+				continue
+			}
+			stack = append(stack, name)
+			args = append(args, 1)
+		case isOperator(char):
+			operator := readOperator(expression, ind)
+			ind += len(operator) - 1
+			popOperatorsWithHigherPrecedence(operator, &stack, &output)
+			stack = append(stack, operator)
 			output.WriteRune(' ')
 		default:
-			output.WriteRune(char)
+			output.WriteByte(char)
 		}
 	}
 
@@ -31,11 +64,11 @@ func InfixToPostfix(expression string) (string, error) {
 		popTopOperator(&stack, &output)
 	}
 
-	return strings.ReplaceAll(strings.TrimSpace(output.String()), "  ", " "), nil
+	return strings.Join(strings.Fields(output.String()), " "), nil
 }
 
-func popUntilOpeningParenthesis(stack *[]rune, output *strings.Builder) error {
-	for len(*stack) > 0 && (*stack)[len(*stack)-1] != '(' {
+func popUntilOpeningParenthesis(stack *[]string, output *strings.Builder) error {
+	for len(*stack) > 0 && (*stack)[len(*stack)-1] != "(" {
 		popTopOperator(stack, output)
 	}
 	if len(*stack) == 0 {
@@ -45,24 +78,47 @@ func popUntilOpeningParenthesis(stack *[]rune, output *strings.Builder) error {
 	return nil
 }
 
-func popOperatorsWithHigherPrecedence(operator rune, stack *[]rune, output *strings.Builder) {
-	for len(*stack) > 0 && precedence((*stack)[len(*stack)-1]) >= precedence(operator) {
+func popOperatorsWithHigherPrecedence(operator string, stack *[]string, output *strings.Builder) {
+	for len(*stack) > 0 {
+		top := (*stack)[len(*stack)-1]
+		if precedence(top) < precedence(operator) ||
+			(precedence(top) == precedence(operator) && isRightAssociative(operator)) {
+			break
+		}
 		popTopOperator(stack, output)
 	}
 }
 
-func popTopOperator(stack *[]rune, output *strings.Builder) {
+func popTopOperator(stack *[]string, output *strings.Builder) {
 	output.WriteRune(' ')
-	output.WriteRune((*stack)[len(*stack)-1])
+	output.WriteString((*stack)[len(*stack)-1])
 	*stack = (*stack)[:len(*stack)-1]
 }
 
-func precedence(operator rune) int {
+// readOperator reads the operator which starts at the start index of the expression.
+func readOperator(expression string, start int) string {
+	if strings.HasPrefix(expression[start:], "//") {
+		return "//"
+	}
+	return expression[start : start+1]
+}
+
+func isOperator(char byte) bool {
+	return precedence(string(char)) != 0
+}
+
+func isRightAssociative(operator string) bool {
+	return operator == "^"
+}
+
+func precedence(operator string) int {
 	switch operator {
-	case '+', '-':
+	case "+", "-":
 		return 1
-	case '*', '/':
+	case "*", "/", "//", "%":
 		return 2
+	case "^":
+		return 3
 	default:
 		return 0
 	}
@@ -111,6 +111,84 @@ func TestInfixToPostfix(t *testing.T) {
 			wantedExpression: "3 4 * 5 6 * /",
 			err:              nil,
 		},
+		{
+			name:             "Expression with decimal numbers",
+			expression:       "3.5*(4.25-1)",
+			wantedExpression: "3.5 4.25 1 - *",
+			err:              nil,
+		},
+		{
+			name:             "Basic arithmetic expressions - '^'",
+			expression:       "3^4",
+			wantedExpression: "3 4 ^",
+			err:              nil,
+		},
+		{
+			name:             "Basic arithmetic expressions - '%'",
+			expression:       "7%4",
+			wantedExpression: "7 4 %",
+			err:              nil,
+		},
+		{
+			name:             "Basic arithmetic expressions - '//'",
+			expression:       "7//4",
+			wantedExpression: "7 4 //",
+			err:              nil,
+		},
+		{
+			name:             "Exponentiation is right-associative",
+			expression:       "2^3^2",
+			wantedExpression: "2 3 2 ^ ^",
+			err:              nil,
+		},
+		{
+			name:             "Exponentiation with parentheses",
+			expression:       "(2^3)^2",
+			wantedExpression: "2 3 ^ 2 ^",
+			err:              nil,
+		},
+		{
+			name:             "Exponentiation has higher precedence than product",
+			expression:       "2*3^2",
+			wantedExpression: "2 3 2 ^ *",
+			err:              nil,
+		},
+		{
+			name:             "Exponentiation has higher precedence than unary minus from the left",
+			expression:       "0-2^2",
+			wantedExpression: "0 2 2 ^ -",
+			err:              nil,
+		},
+		{
+			name:             "Modulo and integer division are left-associative",
+			expression:       "17%5//2*3",
+			wantedExpression: "17 5 % 2 // 3 *",
+			err:              nil,
+		},
+		{
+			name:             "Modulo with plus operator",
+			expression:       "1+7%3",
+			wantedExpression: "1 7 3 % +",
+			err:              nil,
+		},
+		{
+			name:             "Function call",
+			expression:       "abs(3-5)",
+			wantedExpression: "3 5 - abs:1",
+			err:              nil,
+		},
+		{
+			name:             "Function call with many arguments",
+			expression:       "max(1,2*3,(4+5))*2",
+			wantedExpression: "1 2 3 * 4 5 + max:3 2 *",
+			err:              nil,
+		},
+		{
+			name:             "Nested function calls",
+			expression:       "pow(min(2,3),abs(2))",
+			wantedExpression: "2 3 min:2 2 abs:1 pow:2",
+			err:              nil,
+		},
 		{
 			name:             "Invalid expression",
 			expression:       "5+)+3",
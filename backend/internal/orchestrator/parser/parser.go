@@ -2,80 +2,234 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"unicode"
 )
 
-// ParseExpression parses the expression from the user.
-func ParseExpression(expression string) (string, error) {
-	rawExpression := strings.ReplaceAll(expression, " ", "")
-	if !IsValidExpression(rawExpression) {
-		return "", errors.New("invalid expression")
+// ParseExpression parses the expression from the user and builds its tree.
+// If the expression is invalid, the error is *ParseError with the offset in the expression from the user.
+func ParseExpression(expression string) (*Node, error) {
+	rawExpression, offsets := removeSpaces(expression)
+	err := ValidateExpression(rawExpression)
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.Offset = offsets[parseErr.Offset]
+		}
+		return nil, err
 	}
-	rawExpression = AddBrackets(rawExpression)
+	rawExpression = AddBrackets(addZeroToUnaryPlusAndMinus(rawExpression))
 	result, err := InfixToPostfix(rawExpression)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return BuildTree(result)
+}
+
+// removeSpaces removes spaces from the expression.
+// For every byte of the result offsets hold the index of its character in the expression,
+// the last element of offsets is the number of characters in the expression.
+func removeSpaces(expression string) (string, []int) {
+	var result strings.Builder
+	offsets := make([]int, 0, len(expression)+1)
+	chars := 0
+	for _, char := range expression {
+		if char != ' ' {
+			result.WriteRune(char)
+			for range string(char) {
+				offsets = append(offsets, chars)
+			}
+		}
+		chars++
 	}
-	return result, nil
+	offsets = append(offsets, chars)
+
+	return result.String(), offsets
 }
 
 // IsValidExpression checks whether the eexpression is valid or not.
 func IsValidExpression(expression string) bool {
-	stack := make([]rune, 0)
+	return ValidateExpression(expression) == nil
+}
+
+// bracket is an opening bracket which is not closed yet.
+type bracket struct {
+	offset int
+	// function is the name of the function if the bracket holds arguments of the function call.
+	function string
+	args     int
+}
+
+// ValidateExpression checks the expression without spaces and returns *ParseError if it is invalid.
+// Offset of the error is the byte index in the expression.
+func ValidateExpression(expression string) error {
+	stack := make([]bracket, 0)
 
 	if expression == "" {
-		return false
+		return newParseError(CodeEmptyExpression, 0, "expression is empty", expectedOperand...)
 	}
 
+	// Chars up to skip are already checked, e.g. the second char of "//" or a function name.
+	skip := -1
+
 	for i, char := range expression {
+		if i <= skip {
+			continue
+		}
+
 		switch char {
 		case '(':
-			stack = append(stack, char)
+			if i > 0 && (unicode.IsDigit(rune(expression[i-1])) || expression[i-1] == ')') {
+				return newParseError(CodeUnexpectedToken, i, "unexpected (", expectedAfterOperand(stack)...)
+			}
+			stack = append(stack, bracket{offset: i})
 		case ')':
-			if len(stack) == 0 || stack[len(stack)-1] != '(' || (i > 0 && expression[i-1] == '(') {
-				return false
+			if len(stack) == 0 {
+				return newParseError(CodeUnbalancedParen, i, "closing bracket doesn't have opening one")
+			}
+			if i > 0 && (expression[i-1] == '(' || expression[i-1] == ',') {
+				return newParseError(CodeUnexpectedToken, i, "unexpected )", expectedOperand...)
+			}
+			top := stack[len(stack)-1]
+			if top.function != "" && !isValidArity(top.function, top.args) {
+				return newParseError(
+					CodeWrongArgumentCount, top.offset-len(top.function),
+					fmt.Sprintf("function %s can't take %d arguments", top.function, top.args),
+				)
 			}
 			stack = stack[:len(stack)-1]
-		case '*', '/':
-			if i == 0 || i == len(expression)-1 {
-				return false
+			if i+1 < len(expression) && (isNameChar(rune(expression[i+1])) || contains([]rune{'(', '.'}, rune(expression[i+1]))) {
+				return newParseError(
+					CodeUnexpectedToken, i+1,
+					fmt.Sprintf("unexpected %c", expression[i+1]), expectedAfterOperand(stack)...,
+				)
+			}
+		case ',':
+			if len(stack) == 0 || stack[len(stack)-1].function == "" {
+				return newParseError(CodeUnexpectedToken, i, "comma outside of function call", expectedAfterOperand(stack)...)
+			}
+			if i == len(expression)-1 {
+				return newParseError(CodeUnexpectedEnd, i+1, "expression ends after comma", expectedOperand...)
+			}
+			if contains([]rune{'+', '-', '*', '/', '%', '^', '(', ','}, rune(expression[i-1])) {
+				return newParseError(CodeUnexpectedToken, i, "unexpected ,", expectedOperand...)
 			}
-			if contains([]rune{'+', '-', '*', '/', '(', ' '}, rune(expression[i-1])) ||
-				contains([]rune{'+', '-', '*', '/', ')'}, rune(expression[i+1])) {
-				return false
+			if contains([]rune{'*', '/', '%', '^', ')', ','}, rune(expression[i+1])) {
+				return newParseError(
+					CodeUnexpectedToken, i+1,
+					fmt.Sprintf("unexpected %c", expression[i+1]), expectedOperand...,
+				)
 			}
-			if i+1 < len(expression) && expression[i+1] == '0' {
-				return false
+			stack[len(stack)-1].args++
+		case '*', '/', '%', '^':
+			operator := readOperator(expression, i)
+			end := i + len(operator) - 1
+			skip = end
+			if i == 0 {
+				return newParseError(CodeUnexpectedToken, i, "expression starts with "+operator, expectedOperand...)
+			}
+			if end == len(expression)-1 {
+				return newParseError(CodeUnexpectedEnd, end+1, "expression ends with "+operator, expectedOperand...)
+			}
+			if contains([]rune{'+', '-', '*', '/', '%', '^', '(', ','}, rune(expression[i-1])) {
+				return newParseError(CodeUnexpectedToken, i, "unexpected "+operator, expectedOperand...)
+			}
+			if contains([]rune{'+', '-', '*', '/', '%', '^', ')', ','}, rune(expression[end+1])) {
+				return newParseError(
+					CodeUnexpectedToken, end+1,
+					fmt.Sprintf("unexpected %c", expression[end+1]), expectedOperand...,
+				)
+			}
+			if (char == '/' || char == '%') && isZero(readNumber(expression, end+1)) {
+				return newParseError(CodeDivisionByZeroLiteral, end+1, "division by zero")
 			}
 		case '-', '+':
 			if i == len(expression)-1 {
-				return false
+				return newParseError(CodeUnexpectedEnd, i+1, fmt.Sprintf("expression ends with %c", char), expectedOperand...)
 			}
 
-			if i == 0 || i == 1 || expression[i-1] == '(' {
-				if expression[i+1] == ')' {
-					return false
+			if i == 0 || i == 1 || expression[i-1] == '(' || expression[i-1] == ',' {
+				if expression[i+1] == ')' || expression[i+1] == ',' {
+					return newParseError(
+						CodeUnexpectedToken, i+1,
+						fmt.Sprintf("unexpected %c", expression[i+1]), expectedOperand...,
+					)
 				}
 				continue
 			}
 
-			if contains([]rune{'+', '-', '*', '/', ' '}, rune(expression[i-1])) &&
-				contains([]rune{'+', '-', '*', '/', '(', ' '}, rune(expression[i-2])) {
-				return false
+			if contains([]rune{'+', '-', '*', '/', '%', '^'}, rune(expression[i-1])) &&
+				contains([]rune{'+', '-', '*', '/', '%', '^', '(', ','}, rune(expression[i-2])) {
+				return newParseError(CodeUnexpectedToken, i, fmt.Sprintf("unexpected %c", char), expectedOperand...)
+			}
+		case '.':
+			if i == 0 || i == len(expression)-1 ||
+				!unicode.IsDigit(rune(expression[i-1])) || !unicode.IsDigit(rune(expression[i+1])) {
+				return newParseError(CodeInvalidNumber, i, "point must be between digits")
+			}
+			if strings.Contains(expression[numberStart(expression, i):i], ".") {
+				return newParseError(CodeInvalidNumber, i, "number has more than one point")
 			}
 		default:
+			if isNameStart(char) {
+				name := readName(expression, i)
+				end := i + len(name)
+				if i > 0 && (unicode.IsDigit(rune(expression[i-1])) || expression[i-1] == ')') {
+					return newParseError(CodeUnexpectedToken, i, "unexpected "+name, expectedAfterOperand(stack)...)
+				}
+				if end < len(expression) && expression[end] == '(' {
+					// Function name must be followed by the bracket with arguments.
+					if !IsFunction(name) {
+						return newParseError(CodeUnknownFunction, i, "unknown function "+name, ExpectedFunction)
+					}
+					stack = append(stack, bracket{offset: end, function: name, args: 1})
+					skip = end
+					continue
+				}
+				if IsFunction(name) {
+					return newParseError(
+						CodeUnexpectedToken, end,
+						fmt.Sprintf("function %s must be called with brackets", name), ExpectedOpening,
+					)
+				}
+				if end < len(expression) && expression[end] == '.' {
+					return newParseError(CodeUnexpectedToken, end, "unexpected .", expectedAfterOperand(stack)...)
+				}
+				skip = end - 1
+				continue
+			}
 			if !unicode.IsDigit(char) {
-				return false
+				return newParseError(CodeUnexpectedToken, i, fmt.Sprintf("unexpected character %c", char), expectedOperand...)
 			}
-			if i > 0 && expression[i-1] == '0' {
-				return false
+			// Number can't start with zero if it isn't a decimal fraction like 0.5.
+			if i > 0 && expression[i-1] == '0' && numberStart(expression, i-1) == i-1 {
+				return newParseError(CodeInvalidNumber, i-1, "number can't start with zero")
 			}
 		}
 	}
 
-	return len(stack) == 0
+	if len(stack) > 0 {
+		return newParseError(
+			CodeUnbalancedParen, stack[len(stack)-1].offset,
+			"opening bracket doesn't have closing one", ExpectedClosing,
+		)
+	}
+
+	return nil
+}
+
+// expectedAfterOperand returns hints for the position after a number or something like a number.
+func expectedAfterOperand(stack []bracket) []string {
+	expected := []string{ExpectedOperator}
+	if len(stack) > 0 {
+		expected = append(expected, ExpectedClosing)
+	}
+	if len(stack) > 0 && stack[len(stack)-1].function != "" {
+		expected = append(expected, ExpectedComma)
+	}
+	return expected
 }
 
 // AddBrackets adds brackets to espression in order to parallelize some operations.
@@ -96,23 +250,23 @@ func AddBrackets(expression string) string {
 		currentSymbol := parts[ind]
 
 		if ind == 0 &&
-			IsNumber(currentSymbol) &&
-			IsNumber(parts[ind+1]) &&
+			isOperand(currentSymbol) &&
+			isOperand(parts[ind+1]) &&
 			sliceOfOrdersPlusMinus[indForOrdersPlusMinus+1] == '+' {
 
 			result += "(" + currentSymbol + currentOperator + parts[ind+1] + ")"
 			indForOrdersPlusMinus++
 			ind++
 		} else if ind == 0 &&
-			((IsNumber(currentSymbol) && !IsNumber(parts[ind+1])) ||
-				!IsNumber(currentSymbol)) {
+			((isOperand(currentSymbol) && !isOperand(parts[ind+1])) ||
+				!isOperand(currentSymbol)) {
 
 			result += currentSymbol
 		} else if ind == 0 {
 			result += currentSymbol
 		} else if ind+1 < length &&
-			IsNumber(currentSymbol) &&
-			IsNumber(parts[ind+1]) &&
+			isOperand(currentSymbol) &&
+			isOperand(parts[ind+1]) &&
 			currentOperator == "+" &&
 			(indForOrdersPlusMinus+2 >= len(sliceOfOrdersPlusMinus) ||
 				sliceOfOrdersPlusMinus[indForOrdersPlusMinus+2] == '+') {
@@ -134,32 +288,28 @@ func AddBrackets(expression string) string {
 	return result
 }
 
+// addZeroToUnaryPlusAndMinus turns unary plus and minus into binary ones, e.g. -3 into 0-3.
 func addZeroToUnaryPlusAndMinus(expression string) string {
 	var result strings.Builder
-	length := len(expression)
-	ind := 0
-	for ind < length {
-		if ind+1 < length && contains([]rune{'+', '-', '*', '/'}, rune(expression[ind])) && expression[ind+1] == '+' {
-			result.WriteRune(rune(expression[ind]))
-			result.WriteRune('0')
-			result.WriteRune('+')
-			ind++
-		} else if ind == 0 && expression[ind] == '+' {
-			result.WriteRune('0')
-			result.WriteRune('+')
-		} else if ind+1 < length && contains([]rune{'+', '-', '*', '/'}, rune(expression[ind])) && expression[ind+1] == '-' {
-			result.WriteRune(rune(expression[ind]))
+	for ind := 0; ind < len(expression); ind++ {
+		char := expression[ind]
+		switch {
+		case char != '+' && char != '-':
+			result.WriteByte(char)
+		case ind == 0 || contains([]rune{'+', '(', ','}, rune(expression[ind-1])):
 			result.WriteRune('0')
-			result.WriteRune('-')
-			ind++
-		} else if ind == 0 && expression[ind] == '-' {
+			result.WriteByte(char)
+		case expression[ind-1] == '-':
+			// a--b is a-0+b, and a-+b is a-0-b.
 			result.WriteRune('0')
-			result.WriteRune('-')
-		} else {
-			result.WriteRune(rune(expression[ind]))
+			if char == '-' {
+				result.WriteRune('+')
+			} else {
+				result.WriteRune('-')
+			}
+		default:
+			result.WriteByte(char)
 		}
-		ind++
-		// log.Println(result.String())
 	}
 	return result.String()
 }
@@ -174,6 +324,33 @@ func orderPlusMinus(expression string) []rune {
 	return res
 }
 
+// readNumber reads the number which starts at the start index of the expression.
+func readNumber(expression string, start int) string {
+	end := start
+	for end < len(expression) && (unicode.IsDigit(rune(expression[end])) || expression[end] == '.') {
+		end++
+	}
+	return expression[start:end]
+}
+
+// numberStart finds the index where the number containing the ind index starts.
+func numberStart(expression string, ind int) int {
+	for ind > 0 && (unicode.IsDigit(rune(expression[ind-1])) || expression[ind-1] == '.') {
+		ind--
+	}
+	return ind
+}
+
+func isZero(number string) bool {
+	value, err := strconv.ParseFloat(number, 64)
+	return err == nil && value == 0
+}
+
+// isOperand checks if s is a number or a variable.
+func isOperand(s string) bool {
+	return IsNumber(s) || IsVariable(s)
+}
+
 // IsNumber checks if s is a number.
 func IsNumber(s string) bool {
 	_, err := strconv.ParseFloat(s, 64)
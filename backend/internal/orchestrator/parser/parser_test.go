@@ -35,10 +35,22 @@ func TestParseExpression(t *testing.T) {
 		},
 		{
 			name:             "Invalid expression with invalid characters",
-			expression:       "3+x+4*5",
+			expression:       "3+#+4*5",
 			wantedExpression: "",
 			err:              errors.New("invalid expression"),
 		},
+		{
+			name:             "Expression with variables",
+			expression:       "a*x + b + c + 1",
+			wantedExpression: "a x * b c + + 1 +",
+			err:              nil,
+		},
+		{
+			name:             "Expression with variables in function call",
+			expression:       "max(rate_1, rate_2) * 2",
+			wantedExpression: "rate_1 rate_2 max:2 2 *",
+			err:              nil,
+		},
 		{
 			name:             "Expression with leading spaces",
 			expression:       "   3+4*5",
@@ -117,12 +129,64 @@ func TestParseExpression(t *testing.T) {
 			wantedExpression: "42",
 			err:              nil,
 		},
+		{
+			name:             "Expression with decimal numbers",
+			expression:       "7/2.5+0.25",
+			wantedExpression: "7 2.5 / 0.25 +",
+			err:              nil,
+		},
+		{
+			name:             "Expression with new operators",
+			expression:       "2^3^2-17//5%3",
+			wantedExpression: "2 3 2 ^ ^ 17 5 // 3 % -",
+			err:              nil,
+		},
+		{
+			name:             "Expression with function calls",
+			expression:       "max(3, 4*2) + abs(-5)",
+			wantedExpression: "3 4 2 * max:2 0 5 - abs:1 +",
+			err:              nil,
+		},
+		{
+			name:             "Expression with nested function calls",
+			expression:       "sqrt(pow(3, 2) + min(16, 25, 36))",
+			wantedExpression: "3 2 pow:2 16 25 36 min:3 + sqrt:1",
+			err:              nil,
+		},
+		{
+			name:             "Expression with double minus",
+			expression:       "4--2",
+			wantedExpression: "4 0 - 2 +",
+			err:              nil,
+		},
+		{
+			name:             "Expression with unknown function",
+			expression:       "foo(1)",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Expression with integer division by zero",
+			expression:       "7//0",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Expression with division by decimal zero",
+			expression:       "7/0.0",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
 	}
 
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := parser.ParseExpression(tc.expression)
+			tree, err := parser.ParseExpression(tc.expression)
+			got := ""
+			if tree != nil {
+				got = tree.Postfix()
+			}
 			if got != tc.wantedExpression {
 				t.Errorf(
 					"ParseExpression(%v) = %v, %v; want %v, but got %v",
@@ -151,6 +215,117 @@ func TestParseExpression(t *testing.T) {
 
 }
 
+func TestParseExpressionErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		code       parser.ErrorCode
+		offset     int
+		expected   []string
+	}{
+		{
+			name:       "Empty expression",
+			expression: "",
+			code:       parser.CodeEmptyExpression,
+			offset:     0,
+			expected:   []string{"number", "variable", "function", "("},
+		},
+		{
+			name:       "Two operators in a row",
+			expression: "2 + * 3",
+			code:       parser.CodeUnexpectedToken,
+			offset:     4,
+			expected:   []string{"number", "variable", "function", "("},
+		},
+		{
+			name:       "Opening bracket without closing one",
+			expression: "(1 + (2 * 3)",
+			code:       parser.CodeUnbalancedParen,
+			offset:     0,
+			expected:   []string{")"},
+		},
+		{
+			name:       "Closing bracket without opening one",
+			expression: "1 + 2)",
+			code:       parser.CodeUnbalancedParen,
+			offset:     5,
+		},
+		{
+			name:       "Division by zero",
+			expression: "7 / 0.0",
+			code:       parser.CodeDivisionByZeroLiteral,
+			offset:     4,
+		},
+		{
+			name:       "Expression ends with operator",
+			expression: "7 //",
+			code:       parser.CodeUnexpectedEnd,
+			offset:     4,
+			expected:   []string{"number", "variable", "function", "("},
+		},
+		{
+			name:       "Unknown character",
+			expression: "1 + №",
+			code:       parser.CodeUnexpectedToken,
+			offset:     4,
+			expected:   []string{"number", "variable", "function", "("},
+		},
+		{
+			name:       "Number after bracket",
+			expression: "max(1, 2) 3",
+			code:       parser.CodeUnexpectedToken,
+			offset:     10,
+			expected:   []string{"operator"},
+		},
+		{
+			name:       "Unknown function",
+			expression: "1 + foo(2)",
+			code:       parser.CodeUnknownFunction,
+			offset:     4,
+			expected:   []string{"function"},
+		},
+		{
+			name:       "Wrong number of arguments",
+			expression: "1 + sqrt(2, 3)",
+			code:       parser.CodeWrongArgumentCount,
+			offset:     4,
+		},
+		{
+			name:       "Number with two points",
+			expression: "1.2.3",
+			code:       parser.CodeInvalidNumber,
+			offset:     3,
+		},
+		{
+			name:       "Variable after number in function call",
+			expression: "max(2 x)",
+			code:       parser.CodeUnexpectedToken,
+			offset:     6,
+			expected:   []string{"operator", ")", ","},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parser.ParseExpression(tc.expression)
+			var parseErr *parser.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseExpression(%v) error = %v; want *ParseError", tc.expression, err)
+			}
+			if parseErr.Code != tc.code || parseErr.Offset != tc.offset {
+				t.Errorf(
+					"ParseExpression(%v) error = %v at %v; want %v at %v",
+					tc.expression, parseErr.Code, parseErr.Offset, tc.code, tc.offset,
+				)
+			}
+			if strings.Join(parseErr.Expected, " ") != strings.Join(tc.expected, " ") {
+				t.Errorf("ParseExpression(%v) expected = %v; want %v", tc.expression, parseErr.Expected, tc.expected)
+			}
+		})
+	}
+}
+
 func TestIsValidExpression(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -242,6 +417,131 @@ func TestIsValidExpression(t *testing.T) {
 			expression: "3+(-)",
 			want:       false,
 		},
+		{
+			name:       "Valid expression with zeros inside numbers",
+			expression: "100+205*10",
+			want:       true,
+		},
+		{
+			name:       "Valid expression with decimal numbers",
+			expression: "3.5+0.25*10.05",
+			want:       true,
+		},
+		{
+			name:       "Valid expression: division by decimal number less than one",
+			expression: "7/0.5",
+			want:       true,
+		},
+		{
+			name:       "Valid expression: product by zero",
+			expression: "7*0",
+			want:       true,
+		},
+		{
+			name:       "Invalid expression: division by decimal zero",
+			expression: "7/0.00",
+			want:       false,
+		},
+		{
+			name:       "Valid expression with exponentiation, modulo and integer division",
+			expression: "2^10%7+9//2",
+			want:       true,
+		},
+		{
+			name:       "Invalid expression: modulo by zero",
+			expression: "7%0",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: two exponentiation operators in a row",
+			expression: "2^^3",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: three slashes",
+			expression: "7///2",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: integer division at the end",
+			expression: "7//",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: number without integer part",
+			expression: "3+.5",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: number without fractional part",
+			expression: "3.+5",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: number with two points",
+			expression: "1.2.3+5",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: decimal number with leading zero",
+			expression: "00.5+5",
+			want:       false,
+		},
+		{
+			name:       "Valid expression with function calls",
+			expression: "max(3,4*2,-1)+abs(-5)*sqrt((16))",
+			want:       true,
+		},
+		{
+			name:       "Invalid expression: function without arguments",
+			expression: "max()",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: missing argument",
+			expression: "max(1,)",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: too many arguments",
+			expression: "sqrt(1,2)",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: too few arguments",
+			expression: "pow(2)",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: function without brackets",
+			expression: "abs+5",
+			want:       false,
+		},
+		{
+			name:       "Valid expression with variables",
+			expression: "a*x+b_2-abs(y)",
+			want:       true,
+		},
+		{
+			name:       "Invalid expression: number before variable",
+			expression: "2x+1",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: variable with point",
+			expression: "x.5+1",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: number before function",
+			expression: "2max(1,2)",
+			want:       false,
+		},
+		{
+			name:       "Invalid expression: comma outside function call",
+			expression: "(1,2)",
+			want:       false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -291,6 +591,16 @@ func TestAddBrackets(t *testing.T) {
 			expression:       "1+1+2+2+3+3",
 			wantedExpression: "(1+1)+(2+2)+(3+3)",
 		},
+		{
+			name:             "Double minus",
+			expression:       "3--4*5",
+			wantedExpression: "(3-0)+4*5",
+		},
+		{
+			name:             "Unary minus in function arguments",
+			expression:       "max(-3,-4)",
+			wantedExpression: "max(0-3,0-4)",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// Node is a node of the expression tree.
+//
+// Leaves hold a number or a variable in Value, inner nodes hold an Operator or a function name
+// that has to be applied to the Operands.
+// Nodes are numbered in postfix order starting from 1,
+// so the same expression always gets the same node IDs.
+type Node struct {
+	ID       int32
+	Value    string
+	Operator string
+	Operands []*Node
+}
+
+// IsLeaf checks if node is a number that doesn't need to be computed.
+func (n *Node) IsLeaf() bool {
+	return n.Operator == ""
+}
+
+// Postfix returns the expression of the subtree in postfix record.
+func (n *Node) Postfix() string {
+	parts := make([]string, 0)
+	n.walk(func(node *Node) {
+		switch {
+		case node.IsLeaf():
+			parts = append(parts, node.Value)
+		case IsFunction(node.Operator):
+			parts = append(parts, functionToken(node.Operator, len(node.Operands)))
+		default:
+			parts = append(parts, node.Operator)
+		}
+	})
+
+	return strings.Join(parts, " ")
+}
+
+// Nodes returns all nodes of the subtree in postfix order.
+func (n *Node) Nodes() []*Node {
+	nodes := make([]*Node, 0)
+	n.walk(func(node *Node) {
+		nodes = append(nodes, node)
+	})
+
+	return nodes
+}
+
+func (n *Node) walk(visit func(node *Node)) {
+	for _, operand := range n.Operands {
+		operand.walk(visit)
+	}
+	visit(n)
+}
+
+// BuildTree builds the expression tree from the expression in postfix record.
+func BuildTree(parseExpression string) (*Node, error) {
+	stack := make([]*Node, 0)
+
+	for ind, token := range strings.Fields(parseExpression) {
+		node := &Node{ID: int32(ind + 1)}
+
+		if IsNumber(token) || IsVariable(token) {
+			node.Value = token
+			stack = append(stack, node)
+			continue
+		}
+
+		operator, args := token, 2
+		if name, fnArgs, ok := parseFunctionToken(token); ok {
+			operator, args = name, fnArgs
+		} else if precedence(token) == 0 {
+			return nil, errors.New("invalid expression")
+		}
+		if len(stack) < args {
+			return nil, errors.New("invalid expression")
+		}
+
+		node.Operator = operator
+		node.Operands = append([]*Node{}, stack[len(stack)-args:]...)
+		stack = append(stack[:len(stack)-args], node)
+	}
+
+	if len(stack) != 1 {
+		return nil, errors.New("invalid expression")
+	}
+
+	return stack[0], nil
+}
+
+// Token returns "<operand> ... <operand> <operator>" to compute the operator of the node,
+// operands are given in the same order as the node's Operands.
+func Token(operator string, operands []string) string {
+	return strings.Join(operands, " ") + " " + operator
+}
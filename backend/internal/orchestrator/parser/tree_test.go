@@ -0,0 +1,179 @@
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
+)
+
+func TestBuildTree(t *testing.T) {
+	testCases := []struct {
+		name             string
+		expression       string
+		wantedExpression string
+		wantedIDs        []int32
+		err              error
+	}{
+		{
+			name:             "Single number",
+			expression:       "42",
+			wantedExpression: "42",
+			wantedIDs:        []int32{1},
+			err:              nil,
+		},
+		{
+			name:             "One operator",
+			expression:       "1 2 +",
+			wantedExpression: "1 2 +",
+			wantedIDs:        []int32{1, 2, 3},
+			err:              nil,
+		},
+		{
+			name:             "Duplicate subexpressions get different IDs",
+			expression:       "2 2 + 2 2 + *",
+			wantedExpression: "2 2 + 2 2 + *",
+			wantedIDs:        []int32{1, 2, 3, 4, 5, 6, 7},
+			err:              nil,
+		},
+		{
+			name:             "Negative numbers",
+			expression:       "-3 4 -",
+			wantedExpression: "-3 4 -",
+			wantedIDs:        []int32{1, 2, 3},
+			err:              nil,
+		},
+		{
+			name:             "Two-char operator",
+			expression:       "7 2 //",
+			wantedExpression: "7 2 //",
+			wantedIDs:        []int32{1, 2, 3},
+			err:              nil,
+		},
+		{
+			name:             "Function with many arguments",
+			expression:       "1 2 3 max:3 sqrt:1",
+			wantedExpression: "1 2 3 max:3 sqrt:1",
+			wantedIDs:        []int32{1, 2, 3, 4, 5},
+			err:              nil,
+		},
+		{
+			name:             "Function with wrong number of arguments",
+			expression:       "1 2 sqrt:2",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Function without number of arguments",
+			expression:       "1 abs",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Missing operand",
+			expression:       "1 +",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Missing operator",
+			expression:       "1 2 3 +",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Unknown operator",
+			expression:       "1 2 x",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+		{
+			name:             "Empty expression",
+			expression:       "",
+			wantedExpression: "",
+			err:              errors.New("invalid expression"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := parser.BuildTree(tc.expression)
+			got := ""
+			gotIDs := []int32{}
+			if tree != nil {
+				got = tree.Postfix()
+				for _, node := range tree.Nodes() {
+					gotIDs = append(gotIDs, node.ID)
+				}
+			}
+			if got != tc.wantedExpression {
+				t.Errorf("BuildTree(%v) = %v, %v; want %v", tc.expression, got, err, tc.wantedExpression)
+			}
+			if tc.err == nil && !idsEqual(gotIDs, tc.wantedIDs) {
+				t.Errorf("BuildTree(%v) node IDs = %v; want %v", tc.expression, gotIDs, tc.wantedIDs)
+			}
+			if tc.err != nil && (err == nil || !strings.Contains(err.Error(), tc.err.Error())) {
+				t.Errorf(
+					"BuildTree(%v) = %v, %v; expected error containing '%v', but got %v",
+					tc.expression, got, err,
+					tc.err, err,
+				)
+			} else if tc.err == nil && err != nil {
+				t.Errorf("BuildTree(%v) = %v, %v; expected no error, but got %v", tc.expression, got, err, err)
+			}
+		})
+	}
+}
+
+func TestBuildTreeStructure(t *testing.T) {
+	tree, err := parser.BuildTree("2 2 + 2 2 + *")
+	if err != nil {
+		t.Fatalf("BuildTree returned error: %v", err)
+	}
+
+	if tree.Operator != "*" || tree.ID != 7 {
+		t.Fatalf("root = %+v; want operator * with ID 7", tree)
+	}
+	if len(tree.Operands) != 2 {
+		t.Fatalf("root has %d operands; want 2", len(tree.Operands))
+	}
+
+	left, right := tree.Operands[0], tree.Operands[1]
+	if left.ID != 3 || right.ID != 6 {
+		t.Errorf("operands IDs = %d, %d; want 3, 6", left.ID, right.ID)
+	}
+	if left.Postfix() != "2 2 +" || right.Postfix() != "2 2 +" {
+		t.Errorf("operands = %v, %v; want 2 2 +, 2 2 +", left.Postfix(), right.Postfix())
+	}
+	if !left.Operands[0].IsLeaf() || left.IsLeaf() {
+		t.Errorf("IsLeaf is wrong for %+v", left)
+	}
+}
+
+func TestToken(t *testing.T) {
+	got := parser.Token("-", []string{"10", "-4"})
+	if got != "10 -4 -" {
+		t.Errorf("Token(-, [10 -4]) = %v; want 10 -4 -", got)
+	}
+
+	got = parser.Token("max", []string{"1", "2", "3"})
+	if got != "1 2 3 max" {
+		t.Errorf("Token(max, [1 2 3]) = %v; want 1 2 3 max", got)
+	}
+}
+
+func idsEqual(slice1, slice2 []int32) bool {
+	if len(slice1) != len(slice2) {
+		return false
+	}
+
+	for i := 0; i < len(slice1); i++ {
+		if slice1[i] != slice2[i] {
+			return false
+		}
+	}
+
+	return true
+}
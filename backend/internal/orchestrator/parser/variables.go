@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// IsVariable checks if s is a name of a variable like x or rate_2.
+// Names of built-in functions can't be used as variables.
+func IsVariable(s string) bool {
+	if s == "" || IsFunction(s) || !isNameStart(rune(s[0])) {
+		return false
+	}
+	for _, char := range s {
+		if !isNameChar(char) {
+			return false
+		}
+	}
+	return true
+}
+
+// Variables returns names of all variables of the subtree without duplicates
+// in order of their appearance.
+func (n *Node) Variables() []string {
+	variables := make([]string, 0)
+	seen := make(map[string]bool)
+	n.walk(func(node *Node) {
+		if node.IsLeaf() && IsVariable(node.Value) && !seen[node.Value] {
+			seen[node.Value] = true
+			variables = append(variables, node.Value)
+		}
+	})
+
+	return variables
+}
+
+// Bind replaces variables of the subtree with their values from bindings.
+// If some variables don't have values, the tree isn't changed and an error is returned.
+func (n *Node) Bind(bindings map[string]string) error {
+	unbound := UnboundVariables(n, bindings)
+	if len(unbound) > 0 {
+		return fmt.Errorf("unbound variables: %s", strings.Join(unbound, ", "))
+	}
+
+	n.walk(func(node *Node) {
+		if node.IsLeaf() && IsVariable(node.Value) {
+			node.Value = bindings[node.Value]
+		}
+	})
+
+	return nil
+}
+
+// UnboundVariables returns variables of the tree which don't have values in bindings.
+func UnboundVariables(tree *Node, bindings map[string]string) []string {
+	unbound := make([]string, 0)
+	for _, variable := range tree.Variables() {
+		if _, ok := bindings[variable]; !ok {
+			unbound = append(unbound, variable)
+		}
+	}
+
+	return unbound
+}
+
+func isNameStart(char rune) bool {
+	return unicode.IsLetter(char) || char == '_'
+}
+
+func isNameChar(char rune) bool {
+	return isNameStart(char) || unicode.IsDigit(char)
+}
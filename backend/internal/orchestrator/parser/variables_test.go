@@ -0,0 +1,93 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/orchestrator/parser"
+)
+
+func TestIsVariable(t *testing.T) {
+	for name, want := range map[string]bool{
+		"x":      true,
+		"rate_2": true,
+		"_tmp":   true,
+		"2x":     false,
+		"max":    false,
+		"a-b":    false,
+		"":       false,
+	} {
+		if got := parser.IsVariable(name); got != want {
+			t.Errorf("IsVariable(%v) = %v; want %v", name, got, want)
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	testCases := []struct {
+		name             string
+		expression       string
+		bindings         map[string]string
+		wantedExpression string
+		err              string
+	}{
+		{
+			name:             "All variables are bound",
+			expression:       "a x * b +",
+			bindings:         map[string]string{"a": "2", "x": "-3", "b": "1.5"},
+			wantedExpression: "2 -3 * 1.5 +",
+		},
+		{
+			name:             "Same variable is used twice",
+			expression:       "x x *",
+			bindings:         map[string]string{"x": "4"},
+			wantedExpression: "4 4 *",
+		},
+		{
+			name:             "Extra bindings are ignored",
+			expression:       "1 2 +",
+			bindings:         map[string]string{"x": "4"},
+			wantedExpression: "1 2 +",
+		},
+		{
+			name:             "Unbound variables",
+			expression:       "a x * b +",
+			bindings:         map[string]string{"x": "4"},
+			wantedExpression: "a x * b +",
+			err:              "unbound variables: a, b",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := parser.BuildTree(tc.expression)
+			if err != nil {
+				t.Fatalf("BuildTree(%v) returned error: %v", tc.expression, err)
+			}
+
+			err = tree.Bind(tc.bindings)
+			if tc.err == "" && err != nil {
+				t.Errorf("Bind(%v) returned error: %v", tc.bindings, err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("Bind(%v) = %v; expected error containing '%v'", tc.bindings, err, tc.err)
+			}
+			if got := tree.Postfix(); got != tc.wantedExpression {
+				t.Errorf("Bind(%v) = %v; want %v", tc.bindings, got, tc.wantedExpression)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tree, err := parser.ParseExpression("b*x + a*x + max(c, 1)")
+	if err != nil {
+		t.Fatalf("ParseExpression returned error: %v", err)
+	}
+
+	got := strings.Join(tree.Variables(), " ")
+	if got != "b x a c" {
+		t.Errorf("Variables() = %v; want b x a c", got)
+	}
+}
@@ -1,43 +1,184 @@
 package rabbitmq
 
 import (
+	"errors"
 	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/streadway/amqp"
 )
 
+// AMQPConsumer consumes messages of the queue and resumes consumption
+// on new channel when the channel or the connection drops.
 type AMQPConsumer struct {
 	log      *slog.Logger
 	amqpCfg  *AMQPConfig
-	Queue    amqp.Queue
-	Channel  *amqp.Channel
-	Messages <-chan amqp.Delivery
+	queue    brokers.Queue
+	Messages <-chan brokers.Delivery
+	// failures publishes failed messages again in confirm mode.
+	failures *AMQPProducer
+
+	mu        sync.Mutex
+	channel   *amqp.Channel
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Headers of failed messages.
+const (
+	headerQueue   = "x-original-queue"
+	headerReason  = "x-failure-reason"
+	headerRetries = "x-retry-count"
+)
+
+// amqpDelivery adapts amqp.Delivery to brokers.Delivery.
+type amqpDelivery struct {
+	delivery amqp.Delivery
+	amqpCfg  *AMQPConfig
+	failures *AMQPProducer
+	// queue is the name of the queue, the routing key of the message may differ from it.
+	queue string
+}
+
+// Body returns the content of the message.
+func (ad amqpDelivery) Body() []byte {
+	return ad.delivery.Body
+}
+
+// Ack acknowledges the message.
+func (ad amqpDelivery) Ack() error {
+	return staleDelivery(ad.delivery.Ack(false))
+}
+
+// Nack rejects the message, with requeue it's delivered again.
+func (ad amqpDelivery) Nack(requeue bool) error {
+	return staleDelivery(ad.delivery.Nack(false, requeue))
+}
+
+// Fail publishes the message again with the failure in headers and acknowledges it
+// when the broker confirms the new one. After MaxRetries failures the message
+// is published to the dead-letter exchange. If it can't be published, it's requeued as it is.
+func (ad amqpDelivery) Fail(reason string) error {
+	failure := ad.Failure()
+	failure.Reason = reason
+	failure.Retries++
+	if failure.Queue == "" {
+		failure.Queue = ad.queue
+	}
+
+	exchange := ""
+	if failure.Retries > ad.amqpCfg.options.MaxRetries {
+		exchange = ad.amqpCfg.options.DeadLetterExchange
+		ad.amqpCfg.log.Warn("message is dead-lettered", slog.String("queue", failure.Queue), slog.String("reason", reason))
+	}
+
+	headers := amqp.Table{}
+	for key, value := range ad.delivery.Headers {
+		headers[key] = value
+	}
+	headers[headerQueue] = failure.Queue
+	headers[headerReason] = failure.Reason
+	headers[headerRetries] = int32(failure.Retries)
+
+	err := ad.failures.publish(exchange, failure.Queue, amqp.Publishing{
+		ContentType:  ad.delivery.ContentType,
+		DeliveryMode: ad.delivery.DeliveryMode,
+		Headers:      headers,
+		Body:         ad.delivery.Body,
+	})
+	if err != nil {
+		nackErr := ad.delivery.Nack(false, true)
+		if nackErr != nil {
+			return staleDelivery(nackErr)
+		}
+		return err
+	}
+
+	return staleDelivery(ad.delivery.Ack(false))
+}
+
+// Failure returns the last failure of the message from its headers.
+func (ad amqpDelivery) Failure() brokers.Failure {
+	failure := brokers.Failure{}
+	failure.Queue, _ = ad.delivery.Headers[headerQueue].(string)
+	failure.Reason, _ = ad.delivery.Headers[headerReason].(string)
+
+	switch retries := ad.delivery.Headers[headerRetries].(type) {
+	case int32:
+		failure.Retries = int(retries)
+	case int64:
+		failure.Retries = int(retries)
+	}
+
+	return failure
+}
+
+// staleDelivery reports that the channel of the message is closed,
+// RabbitMQ delivers such messages again after reconnection.
+func staleDelivery(err error) error {
+	if errors.Is(err, amqp.ErrClosed) {
+		return brokers.ErrStaleDelivery
+	}
+	return err
 }
 
 // NewAMQPConsumer creates new Consumer for AMQP protocol.
+// The exclusive queue is declared again on every channel.
 func NewAMQPConsumer(
 	log *slog.Logger,
 	amqpCfg *AMQPConfig,
-	queueName string,
+	queue brokers.Queue,
 ) (*AMQPConsumer, error) {
-	chCons, err := amqpCfg.conn.Channel()
+	failures, err := NewAMQPProducer(log, amqpCfg, "")
 	if err != nil {
-		log.Error("can't create a channel from RabbitMQ", sl.Err(err))
 		return nil, err
 	}
-	queue, err := chCons.QueueDeclare(
-		queueName,
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+
+	deliveries := make(chan brokers.Delivery)
+
+	ac := &AMQPConsumer{
+		log:      log,
+		amqpCfg:  amqpCfg,
+		queue:    queue,
+		Messages: deliveries,
+		failures: failures,
+		done:     make(chan struct{}),
+	}
+
+	queueName, msgs, err := ac.consume()
 	if err != nil {
-		log.Error("can't create a RabbitMQ queue", sl.Err(err))
+		failures.Close()
 		return nil, err
 	}
+
+	go ac.run(queueName, msgs, deliveries)
+
+	return ac, nil
+}
+
+// consume opens new channel, declares the queue and starts consuming it.
+// It returns the name of the queue which is given by RabbitMQ to the queue without name.
+func (ac *AMQPConsumer) consume() (string, <-chan amqp.Delivery, error) {
+	chCons, err := ac.amqpCfg.channel(0)
+	if err != nil {
+		ac.log.Error("can't create a channel from RabbitMQ", sl.Err(err))
+		return "", nil, err
+	}
+	err = ac.amqpCfg.declareExchanges(chCons)
+	if err != nil {
+		ac.log.Error("can't create RabbitMQ exchanges", sl.Err(err))
+		chCons.Close()
+		return "", nil, err
+	}
+	queue, err := ac.amqpCfg.declareQueue(chCons, ac.queue)
+	if err != nil {
+		ac.log.Error("can't create a RabbitMQ queue", sl.Err(err))
+		chCons.Close()
+		return "", nil, err
+	}
 	msgs, err := chCons.Consume(
 		queue.Name,
 		"",
@@ -48,24 +189,88 @@ func NewAMQPConsumer(
 		nil,
 	)
 	if err != nil {
-		log.Error("can't create a channel to consume messages from RabbitMQ", sl.Err(err))
-		return nil, err
+		ac.log.Error("can't create a channel to consume messages from RabbitMQ", sl.Err(err))
+		chCons.Close()
+		return "", nil, err
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	select {
+	case <-ac.done:
+		// Closed while consuming was resumed.
+		chCons.Close()
+		return "", nil, ErrClosed
+	default:
+	}
+	ac.channel = chCons
+
+	return queue.Name, msgs, nil
+}
+
+// run forwards messages and consumes again when they stop till the Consumer is closed.
+func (ac *AMQPConsumer) run(
+	queueName string,
+	msgs <-chan amqp.Delivery,
+	deliveries chan<- brokers.Delivery,
+) {
+	defer close(deliveries)
+
+	for {
+		for msg := range msgs {
+			select {
+			case deliveries <- amqpDelivery{
+				delivery: msg,
+				amqpCfg:  ac.amqpCfg,
+				failures: ac.failures,
+				queue:    queueName,
+			}:
+			case <-ac.done:
+				return
+			}
+		}
+
+		select {
+		case <-ac.done:
+			return
+		default:
+		}
+
+		ac.log.Warn("consuming is interrupted, resuming", slog.String("queue", queueName))
+
+		for attempt := 0; ; attempt++ {
+			var err error
+			queueName, msgs, err = ac.consume()
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrClosed) {
+				return
+			}
+
+			select {
+			case <-time.After(backoff(attempt, ac.amqpCfg.options.ReconnectMinDelay, ac.amqpCfg.options.ReconnectMaxDelay)):
+			case <-ac.done:
+				return
+			}
+		}
 	}
-	return &AMQPConsumer{
-		log:      log,
-		amqpCfg:  amqpCfg,
-		Queue:    queue,
-		Channel:  chCons,
-		Messages: msgs,
-	}, nil
 }
 
 // GetMessages returns messages from the Consumer channel.
-func (ac *AMQPConsumer) GetMessages() <-chan amqp.Delivery {
+func (ac *AMQPConsumer) GetMessages() <-chan brokers.Delivery {
 	return ac.Messages
 }
 
 // Close closes Consumer channel.
 func (ac *AMQPConsumer) Close() {
-	ac.Channel.Close()
+	ac.closeOnce.Do(func() {
+		close(ac.done)
+		ac.failures.Close()
+
+		ac.mu.Lock()
+		defer ac.mu.Unlock()
+		ac.channel.Close()
+	})
 }
@@ -3,84 +3,259 @@ package rabbitmq
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/messages"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/streadway/amqp"
 )
 
+// pendingNotifications is the capacity of channels of confirms and returns,
+// it must be larger than the number of confirms which came after timeouts.
+const pendingNotifications = 64
+
+var (
+	ErrNotConfirmed   = errors.New("broker rejected message")
+	ErrUnroutable     = errors.New("message can't be routed to queue")
+	ErrConfirmTimeout = errors.New("timeout waiting for confirm")
+	ErrChannelClosed  = errors.New("channel is closed")
+)
+
+// AMQPProducer publishes messages to the exchange with the routing key,
+// the channel is reopened when it or the connection drops.
 type AMQPProducer struct {
-	log     *slog.Logger
-	amqpCfg *AMQPConfig
-	Queue   amqp.Queue
-	Channel *amqp.Channel
+	log        *slog.Logger
+	amqpCfg    *AMQPConfig
+	routingKey string
+	// queues are declared with the channel, so messages aren't lost before consumers start.
+	queues []brokers.Queue
+
+	// mu serializes publishing, so every publisher waits for the confirm of its message.
+	mu          sync.Mutex
+	channel     *amqp.Channel
+	deliveryTag uint64
+	confirms    chan amqp.Confirmation
+	returns     chan amqp.Return
+	closed      chan *amqp.Error
 }
 
-// NewAMQPProducer creates new Producer for AMQP protocol.
-func NewAMQPProducer(log *slog.Logger, amqpCfg *AMQPConfig, queueName string) (*AMQPProducer, error) {
-	chProd, err := amqpCfg.conn.Channel()
+// NewAMQPProducer creates new Producer for AMQP protocol which declares queues.
+// Messages are published in confirm mode with mandatory flag.
+func NewAMQPProducer(
+	log *slog.Logger,
+	amqpCfg *AMQPConfig,
+	routingKey string,
+	queues ...brokers.Queue,
+) (*AMQPProducer, error) {
+	ap := &AMQPProducer{
+		log:        log,
+		amqpCfg:    amqpCfg,
+		routingKey: routingKey,
+		queues:     queues,
+	}
+
+	err := ap.open()
 	if err != nil {
-		log.Error("can't create a channel from RabbitMQ", sl.Err(err))
 		return nil, err
 	}
 
-	queue, err := chProd.QueueDeclare(
-		queueName,
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+	return ap, nil
+}
+
+// open opens new channel in confirm mode and declares queues, it must be called with mu.
+func (ap *AMQPProducer) open() error {
+	chProd, err := ap.amqpCfg.channel(ap.amqpCfg.options.ConfirmTimeout)
 	if err != nil {
-		log.Error("can't create a RabbitMQ queue", sl.Err(err))
-		return nil, err
+		ap.log.Error("can't create a channel from RabbitMQ", sl.Err(err))
+		return err
+	}
+
+	err = ap.amqpCfg.declareExchanges(chProd)
+	if err != nil {
+		ap.log.Error("can't create RabbitMQ exchanges", sl.Err(err))
+		chProd.Close()
+		return err
+	}
+
+	for _, queue := range ap.queues {
+		_, err = ap.amqpCfg.declareQueue(chProd, queue)
+		if err != nil {
+			ap.log.Error("can't create a RabbitMQ queue", sl.Err(err))
+			chProd.Close()
+			return err
+		}
+	}
+
+	err = chProd.Confirm(false)
+	if err != nil {
+		ap.log.Error("can't put channel into confirm mode", sl.Err(err))
+		chProd.Close()
+		return err
 	}
 
-	return &AMQPProducer{
-		log:     log,
-		amqpCfg: amqpCfg,
-		Queue:   queue,
-		Channel: chProd,
-	}, nil
+	ap.channel = chProd
+	ap.deliveryTag = 0
+	ap.confirms = chProd.NotifyPublish(make(chan amqp.Confirmation, pendingNotifications))
+	ap.returns = chProd.NotifyReturn(make(chan amqp.Return, pendingNotifications))
+	ap.closed = chProd.NotifyClose(make(chan *amqp.Error, 1))
+
+	return nil
 }
 
-// PublishExpressionMessage publishes messages to queue.
+// isClosed checks if the channel is closed, it must be called with mu.
+func (ap *AMQPProducer) isClosed() bool {
+	select {
+	case <-ap.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// PublishExpressionMessage publishes messages to the exchange and waits till the broker confirms it.
 func (ap *AMQPProducer) PublishExpressionMessage(msg *messages.ExpressionMessage) error {
 	jsonData, err := json.Marshal(msg)
 	if err != nil {
 		return errors.New("failed to encode message to JSON")
 	}
 
-	err = ap.Channel.Publish(
-		"",
-		ap.Queue.Name,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        jsonData,
-		},
-	)
+	deliveryMode := amqp.Transient
+	if ap.amqpCfg.options.Durable {
+		deliveryMode = amqp.Persistent
+	}
+
+	return ap.publish(ap.amqpCfg.options.Exchange, ap.routingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: deliveryMode,
+		Body:         jsonData,
+	})
+}
+
+// publish publishes the message with mandatory flag and waits till the broker confirms it.
+// The ID of the message is replaced to match it with returns.
+func (ap *AMQPProducer) publish(exchange, routingKey string, publishing amqp.Publishing) error {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
 
+	if ap.isClosed() {
+		ap.log.Warn("channel is closed, reopening", slog.String("routing key", routingKey))
+
+		err := ap.open()
+		if err != nil {
+			return fmt.Errorf("can't reopen channel: %w", err)
+		}
+	}
+
+	// Delivery tags of the channel in confirm mode start with 1,
+	// the tag is also the ID of the message to match it with returns.
+	deliveryTag := ap.deliveryTag + 1
+	messageID := strconv.FormatUint(deliveryTag, 10)
+	publishing.MessageId = messageID
+
+	err := ap.channel.Publish(exchange, routingKey, true, false, publishing)
+	if errors.Is(err, amqp.ErrClosed) {
+		// The message isn't sent, so it's safe to publish it again on new channel.
+		ap.log.Warn("channel is closed, reopening", slog.String("routing key", routingKey))
+
+		err = ap.open()
+		if err != nil {
+			return fmt.Errorf("can't reopen channel: %w", err)
+		}
+
+		deliveryTag = ap.deliveryTag + 1
+		messageID = strconv.FormatUint(deliveryTag, 10)
+		publishing.MessageId = messageID
+
+		err = ap.channel.Publish(exchange, routingKey, true, false, publishing)
+	}
+	if err != nil {
+		ap.log.Error("can't publish message", slog.String("routing key", routingKey), sl.Err(err))
+		return fmt.Errorf("can't publish message to queue: %w", err)
+	}
+	ap.deliveryTag = deliveryTag
+
+	err = ap.waitForConfirm(deliveryTag, messageID)
 	if err != nil {
-		ap.log.Error("can't publish message to queue", slog.String("queue", ap.Queue.Name), sl.Err(err))
-		return errors.New("can't publish message to queue")
+		ap.log.Error("message isn't confirmed", slog.String("routing key", routingKey), sl.Err(err))
+		return fmt.Errorf("can't publish message to queue: %w", err)
 	}
-	ap.log.Info("publishing message to queue", slog.String("queue", ap.Queue.Name))
+
+	ap.log.Info("publishing message", slog.String("routing key", routingKey))
 
 	return nil
 }
 
-// Reconnect reconnects to AMQP instance.
-func (ap *AMQPProducer) Reconnect() (*AMQPProducer, error) {
-	ap.Close()
+// waitForConfirm waits for the confirm of the message.
+func (ap *AMQPProducer) waitForConfirm(deliveryTag uint64, messageID string) error {
+	timer := time.NewTimer(ap.amqpCfg.options.ConfirmTimeout)
+	defer timer.Stop()
 
-	return NewAMQPProducer(ap.log, ap.amqpCfg, ap.Queue.Name)
+	for {
+		select {
+		case confirm, ok := <-ap.confirms:
+			if !ok {
+				return ErrChannelClosed
+			}
+			// Confirms of messages which timed out come late.
+			if confirm.DeliveryTag < deliveryTag {
+				continue
+			}
+			if !confirm.Ack {
+				return ErrNotConfirmed
+			}
+			if ap.returned(messageID) {
+				return ErrUnroutable
+			}
+			return nil
+		case <-timer.C:
+			return ErrConfirmTimeout
+		}
+	}
+}
+
+// returned checks if the message was returned as unroutable.
+// The broker returns the message before its confirm, so the return is already received.
+func (ap *AMQPProducer) returned(messageID string) bool {
+	returned := false
+	for {
+		select {
+		case ret, ok := <-ap.returns:
+			if !ok {
+				return returned
+			}
+			if ret.MessageId == messageID {
+				returned = true
+			}
+		default:
+			return returned
+		}
+	}
+}
+
+// Reconnect reopens the channel of the Producer.
+func (ap *AMQPProducer) Reconnect() (brokers.Producer, error) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	ap.channel.Close()
+
+	err := ap.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return ap, nil
 }
 
 // Close closes Producer channel.
 func (ap *AMQPProducer) Close() {
-	ap.Channel.Close()
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	ap.channel.Close()
 }
@@ -0,0 +1,32 @@
+package rabbitmq
+
+import (
+	"github.com/Prrromanssss/DAEC-fullstack/internal/config"
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
+)
+
+// NewOptions maps the configuration of RabbitMQ to Options.
+func NewOptions(cfg config.RabbitQueue) Options {
+	return Options{
+		Exchange:           cfg.Exchange,
+		Durable:            cfg.Durable,
+		ConfirmTimeout:     cfg.ConfirmTimeout,
+		ReconnectMinDelay:  cfg.ReconnectMinDelay,
+		ReconnectMaxDelay:  cfg.ReconnectMaxDelay,
+		MaxRetries:         cfg.MaxRetries,
+		DeadLetterExchange: cfg.DeadLetterExchange,
+		DeadLetterQueue:    cfg.QueueForDeadLetters,
+	}
+}
+
+// NewTopology maps the configuration of RabbitMQ to queues and routing keys of the broker,
+// the in-memory broker uses the same topology.
+func NewTopology(cfg config.RabbitQueue) brokers.Topology {
+	return brokers.NewTopology(brokers.TopologyOptions{
+		TasksQueue:      cfg.QueueForExpressionsToAgents,
+		ResultsQueue:    cfg.QueueForResultsFromAgents,
+		HeartbeatsQueue: cfg.QueueForHeartbeats,
+		HeartbeatTTL:    cfg.HeartbeatTTL,
+		Keys:            brokers.RoutingKeys(cfg.RoutingKeys),
+	})
+}
@@ -1,19 +1,81 @@
 package rabbitmq
 
 import (
+	"errors"
 	"log/slog"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/brokers"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/streadway/amqp"
 )
 
+var (
+	ErrNotConnected = errors.New("not connected to RabbitMQ")
+	ErrClosed       = errors.New("connection to RabbitMQ is closed")
+)
+
+// ConnectionState is the state of the connection to RabbitMQ.
+type ConnectionState int32
+
+const (
+	StateConnected ConnectionState = iota
+	StateReconnecting
+	StateClosed
+)
+
+// String returns the name of the state.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	default:
+		return "closed"
+	}
+}
+
+// Options configure the exchange, durability and delivery guarantees of queues.
+type Options struct {
+	// Exchange is the direct exchange which routes messages of producers to queues.
+	Exchange string
+	// Durable queues and persistent messages survive restart of RabbitMQ.
+	Durable bool
+	// ConfirmTimeout limits how long a producer waits for the broker to confirm a message.
+	ConfirmTimeout time.Duration
+	// ReconnectMinDelay and ReconnectMaxDelay bound the exponential backoff between dials.
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+	// MaxRetries is how many times the failed message is delivered again
+	// before it's published to DeadLetterExchange, which routes it to DeadLetterQueue.
+	MaxRetries         int
+	DeadLetterExchange string
+	DeadLetterQueue    string
+}
+
+// AMQPConfig is the connection to RabbitMQ which is restored when it drops.
+// Consumers and producers of the connection reopen their channels after that.
 type AMQPConfig struct {
-	log  *slog.Logger
+	log     *slog.Logger
+	url     string
+	options Options
+	state   atomic.Int32
+
+	mu   sync.Mutex
 	conn *amqp.Connection
+	// connected is closed while conn is usable and replaced when it drops.
+	connected chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewAMQPConfig creates new AMQP connection.
-func NewAMQPConfig(log *slog.Logger, amqpUrl string) (*AMQPConfig, error) {
+func NewAMQPConfig(log *slog.Logger, amqpUrl string, options Options) (*AMQPConfig, error) {
 	conn, err := amqp.Dial(amqpUrl)
 	if err != nil {
 		log.Error("can't connect to RabbitMQ", sl.Err(err))
@@ -22,13 +84,227 @@ func NewAMQPConfig(log *slog.Logger, amqpUrl string) (*AMQPConfig, error) {
 
 	log.Info("successfully connected to RabbitMQ instance")
 
-	return &AMQPConfig{
-		log:  log,
-		conn: conn,
-	}, nil
+	ac := &AMQPConfig{
+		log:       log,
+		url:       amqpUrl,
+		options:   options,
+		conn:      conn,
+		connected: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	close(ac.connected)
+
+	go ac.watch(conn)
+
+	return ac, nil
+}
+
+// State returns the state of the connection.
+func (ac *AMQPConfig) State() ConnectionState {
+	return ConnectionState(ac.state.Load())
+}
+
+// watch redials when the connection drops till the AMQPConfig is closed.
+func (ac *AMQPConfig) watch(conn *amqp.Connection) {
+	for {
+		select {
+		case err := <-conn.NotifyClose(make(chan *amqp.Error, 1)):
+			ac.log.Warn("connection to RabbitMQ is lost", slog.Any("reason", err))
+		case <-ac.done:
+			return
+		}
+
+		ac.mu.Lock()
+		ac.connected = make(chan struct{})
+		ac.mu.Unlock()
+		ac.state.Store(int32(StateReconnecting))
+
+		conn = ac.redial()
+		if conn == nil {
+			return
+		}
+
+		ac.mu.Lock()
+		select {
+		case <-ac.done:
+			// Closed while dialing.
+			ac.mu.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		ac.conn = conn
+		close(ac.connected)
+		ac.mu.Unlock()
+		ac.state.Store(int32(StateConnected))
+
+		ac.log.Info("successfully reconnected to RabbitMQ instance")
+	}
+}
+
+// redial dials with exponential backoff, it returns nil if the AMQPConfig is closed.
+func (ac *AMQPConfig) redial() *amqp.Connection {
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-time.After(backoff(attempt, ac.options.ReconnectMinDelay, ac.options.ReconnectMaxDelay)):
+		case <-ac.done:
+			return nil
+		}
+
+		conn, err := amqp.Dial(ac.url)
+		if err == nil {
+			return conn
+		}
+
+		ac.log.Warn("can't reconnect to RabbitMQ", slog.Int("attempt", attempt+1), sl.Err(err))
+	}
+}
+
+// backoff returns the delay before the attempt: minDelay doubled on every attempt
+// but not longer than maxDelay, with jitter so clients don't dial at the same time.
+func backoff(attempt int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := minDelay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// channel opens new channel, if the connection is being restored it waits for timeout,
+// zero timeout waits till the connection is restored or closed.
+func (ac *AMQPConfig) channel(timeout time.Duration) (*amqp.Channel, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	ac.mu.Lock()
+	connected := ac.connected
+	ac.mu.Unlock()
+
+	select {
+	case <-connected:
+	case <-expired:
+		return nil, ErrNotConnected
+	case <-ac.done:
+		return nil, ErrClosed
+	}
+
+	ac.mu.Lock()
+	conn := ac.conn
+	ac.mu.Unlock()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
 }
 
-// Close closes AMQP connection.
+// declareExchanges declares the exchange and the dead-letter exchange with its queue.
+func (ac *AMQPConfig) declareExchanges(ch *amqp.Channel) error {
+	err := ch.ExchangeDeclare(
+		ac.options.Exchange,
+		amqp.ExchangeDirect,
+		ac.options.Durable,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = ch.ExchangeDeclare(
+		ac.options.DeadLetterExchange,
+		amqp.ExchangeFanout,
+		ac.options.Durable,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = ch.QueueDeclare(
+		ac.options.DeadLetterQueue,
+		ac.options.Durable,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(ac.options.DeadLetterQueue, "", ac.options.DeadLetterExchange, false, nil)
+}
+
+// declareQueue declares the queue with durability from options
+// and binds it to the exchange with its routing keys.
+func (ac *AMQPConfig) declareQueue(ch *amqp.Channel, queue brokers.Queue) (amqp.Queue, error) {
+	var args amqp.Table
+	if queue.MessageTTL > 0 {
+		args = amqp.Table{"x-message-ttl": queue.MessageTTL.Milliseconds()}
+	}
+
+	q, err := ch.QueueDeclare(
+		queue.Name,
+		ac.options.Durable && !queue.Exclusive,
+		queue.Exclusive,
+		queue.Exclusive,
+		false,
+		args,
+	)
+	if err != nil {
+		return amqp.Queue{}, err
+	}
+
+	for _, routingKey := range queue.RoutingKeys {
+		err = ch.QueueBind(q.Name, routingKey, ac.options.Exchange, false, nil)
+		if err != nil {
+			return amqp.Queue{}, err
+		}
+	}
+
+	return q, nil
+}
+
+// PurgeQueue deletes all messages of the queue.
+func (ac *AMQPConfig) PurgeQueue(queueName string) error {
+	ch, err := ac.channel(ac.options.ConfirmTimeout)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	_, err = ch.QueuePurge(queueName, false)
+
+	return err
+}
+
+// Close closes AMQP connection and stops restoring it.
 func (ac *AMQPConfig) Close() {
-	ac.conn.Close()
+	ac.closeOnce.Do(func() {
+		close(ac.done)
+		ac.state.Store(int32(StateClosed))
+
+		ac.mu.Lock()
+		defer ac.mu.Unlock()
+		ac.conn.Close()
+	})
 }
@@ -0,0 +1,53 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Prrromanssss/DAEC-fullstack/internal/config"
+)
+
+func TestBackoff(t *testing.T) {
+	minDelay, maxDelay := time.Second, 30*time.Second
+
+	testCases := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{attempt: 0, delay: time.Second},
+		{attempt: 1, delay: 2 * time.Second},
+		{attempt: 3, delay: 8 * time.Second},
+		{attempt: 5, delay: 30 * time.Second},
+		{attempt: 100, delay: 30 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < 10; i++ {
+			got := backoff(tc.attempt, minDelay, maxDelay)
+			if got < tc.delay/2 || got > tc.delay {
+				t.Errorf("backoff(%d) = %v; want between %v and %v", tc.attempt, got, tc.delay/2, tc.delay)
+			}
+		}
+	}
+
+	if got := backoff(3, 0, 0); got != 0 {
+		t.Errorf("backoff with zero delays = %v; want 0", got)
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	cfg := config.RabbitQueue{
+		Exchange:            "daec",
+		DeadLetterExchange:  "daec.dead-letter",
+		QueueForDeadLetters: "Dead letters",
+		MaxRetries:          3,
+	}
+
+	options := NewOptions(cfg)
+	if options.Exchange != "daec" {
+		t.Errorf("Exchange = %q; want %q", options.Exchange, "daec")
+	}
+	if options.DeadLetterExchange != "daec.dead-letter" || options.DeadLetterQueue != "Dead letters" || options.MaxRetries != 3 {
+		t.Errorf("options = %+v; want dead letters of the configuration", options)
+	}
+}
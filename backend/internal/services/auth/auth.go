@@ -2,11 +2,17 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
+	"github.com/Prrromanssss/DAEC-fullstack/internal/domain/roles"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/jwt"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage"
@@ -15,10 +21,14 @@ import (
 )
 
 type Auth struct {
-	log         *slog.Logger
-	usrSaver    UserSaver
-	usrProvider UserProvider
-	tokenTTL    time.Duration
+	log             *slog.Logger
+	usrSaver        UserSaver
+	usrProvider     UserProvider
+	tokenStorage    TokenStorage
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+	keys            *jwt.KeySet
+	adminEmails     []string
 }
 
 type UserSaver interface {
@@ -26,11 +36,25 @@ type UserSaver interface {
 		ctx context.Context,
 		email string,
 		passHash []byte,
+		roles []string,
 	) (uid int32, err error)
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (postgres.User, error)
+	UserByID(ctx context.Context, userID int32) (postgres.User, error)
+}
+
+type TokenStorage interface {
+	SaveRefreshToken(ctx context.Context, token postgres.CreateRefreshTokenParams) error
+	RotateRefreshToken(
+		ctx context.Context,
+		tokenHash string,
+		next postgres.CreateRefreshTokenParams,
+	) (postgres.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string, userID int32) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	DeleteExpiredTokens(ctx context.Context, now time.Time) error
 }
 
 var (
@@ -38,6 +62,7 @@ var (
 	ErrInvalidAppID       = errors.New("invalid app id")
 	ErrUserExists         = errors.New("user already exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidToken       = errors.New("invalid token")
 )
 
 // New returns a new instance of the Auth service.
@@ -45,17 +70,25 @@ func New(
 	log *slog.Logger,
 	userSaver UserSaver,
 	userProvider UserProvider,
+	tokenStorage TokenStorage,
 	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	keys *jwt.KeySet,
+	adminEmails []string,
 ) *Auth {
 	return &Auth{
-		log:         log,
-		usrSaver:    userSaver,
-		usrProvider: userProvider,
-		tokenTTL:    tokenTTL,
+		log:             log,
+		usrSaver:        userSaver,
+		usrProvider:     userProvider,
+		tokenStorage:    tokenStorage,
+		tokenTTL:        tokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		keys:            keys,
+		adminEmails:     adminEmails,
 	}
 }
 
-// Login checks if user with given credentials exists in the system and returns access token.
+// Login checks if user with given credentials exists in the system and returns access and refresh tokens.
 //
 // If user exists, but password is incorrect, returns error.
 // If user doesn't exist, returns error.
@@ -63,7 +96,7 @@ func (a *Auth) Login(
 	ctx context.Context,
 	email string,
 	password string,
-) (string, error) {
+) (string, string, error) {
 	const op = "auth.Login"
 
 	log := a.log.With(
@@ -78,34 +111,64 @@ func (a *Auth) Login(
 		if errors.Is(err, storage.ErrUserNotFound) {
 			a.log.Warn("user not found", sl.Err(err))
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
 
 		a.log.Error("failed to get user", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
 		a.log.Info("invalid credentials", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
 	log.Info("user logged successfully")
 
-	token, err := jwt.NewToken(user, a.tokenTTL)
+	token, err := jwt.NewToken(user, a.tokenTTL, a.keys)
 	if err != nil {
 		a.log.Error("failed to generate token", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	familyID, err := newRefreshToken()
+	if err != nil {
+		a.log.Error("failed to generate refresh token family", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		a.log.Error("failed to generate refresh token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return token, nil
+	now := time.Now().UTC()
+
+	err = a.tokenStorage.SaveRefreshToken(ctx, postgres.CreateRefreshTokenParams{
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.refreshTokenTTL),
+	})
+	if err != nil {
+		a.log.Error("failed to save refresh token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, refreshToken, nil
 }
 
 // RegisterNewUser registers new user in the system and returns user ID.
 // If user with given username already exists, returns error.
+// Users with emails from the admin list get the admin role.
 func (a *Auth) RegisterNewUser(
 	ctx context.Context,
 	email string,
@@ -127,7 +190,12 @@ func (a *Auth) RegisterNewUser(
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := a.usrSaver.SaveUser(ctx, email, passHash)
+	userRoles := []string{roles.User}
+	if slices.Contains(a.adminEmails, email) {
+		userRoles = append(userRoles, roles.Admin)
+	}
+
+	id, err := a.usrSaver.SaveUser(ctx, email, passHash, userRoles)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Warn("user already exists", sl.Err(err))
@@ -143,3 +211,159 @@ func (a *Auth) RegisterNewUser(
 
 	return int64(id), nil
 }
+
+// Refresh exchanges the refresh token for new access and refresh tokens.
+//
+// Every refresh token can be used only once. If the used token is presented again,
+// all refresh tokens issued after the same login are revoked.
+func (a *Auth) Refresh(
+	ctx context.Context,
+	refreshToken string,
+) (string, string, error) {
+	const op = "auth.Refresh"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	newRefreshTok, err := newRefreshToken()
+	if err != nil {
+		log.Error("failed to generate refresh token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	rotated, err := a.tokenStorage.RotateRefreshToken(ctx, hashToken(refreshToken), postgres.CreateRefreshTokenParams{
+		TokenHash: hashToken(newRefreshTok),
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.refreshTokenTTL),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenReused) {
+			log.Warn("refresh token reused, token family is revoked")
+
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Info("refresh token not found")
+
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to rotate refresh token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrProvider.UserByID(ctx, rotated.UserID)
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := jwt.NewToken(user, a.tokenTTL, a.keys)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tokens refreshed", slog.Int("user_id", int(user.UserID)))
+
+	return token, newRefreshTok, nil
+}
+
+// Logout revokes the access token and, if it's given, the refresh token of the user.
+func (a *Auth) Logout(
+	ctx context.Context,
+	token string,
+	refreshToken string,
+) error {
+	const op = "auth.Logout"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := jwt.ParseToken(ctx, token, a.keys)
+	if err != nil {
+		log.Info("invalid token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if claims.ID != "" {
+		err = a.tokenStorage.RevokeToken(ctx, claims.ID, claims.ExpiresAt)
+		if err != nil {
+			log.Error("failed to revoke token", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if refreshToken != "" {
+		err = a.tokenStorage.RevokeRefreshToken(ctx, hashToken(refreshToken), claims.UserID)
+		if err != nil && !errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Error("failed to revoke refresh token", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("user logged out", slog.Int("user_id", int(claims.UserID)))
+
+	return nil
+}
+
+// DeleteExpiredTokens deletes expired refresh tokens and revoked access tokens,
+// which are rejected anyway.
+func (a *Auth) DeleteExpiredTokens(ctx context.Context) error {
+	const op = "auth.DeleteExpiredTokens"
+
+	if err := a.tokenStorage.DeleteExpiredTokens(ctx, time.Now().UTC()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// JWKS returns public keys which verify tokens of the service.
+func (a *Auth) JWKS() (jwt.JWKS, error) {
+	const op = "auth.JWKS"
+
+	jwks, err := a.keys.JWKS()
+	if err != nil {
+		return jwt.JWKS{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return jwks, nil
+}
+
+// ReloadKeys rereads signing keys, so they can be rotated without restart.
+func (a *Auth) ReloadKeys() error {
+	const op = "auth.ReloadKeys"
+
+	if err := a.keys.Reload(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// newRefreshToken returns random opaque token.
+func newRefreshToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashToken returns the hash of the refresh token which is kept in storage instead of the token.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -58,6 +58,29 @@ func (q *Queries) DecrementNumberOfActiveCalculations(ctx context.Context, agent
 	return err
 }
 
+const getAgentByID = `-- name: GetAgentByID :one
+SELECT
+    agent_id, number_of_parallel_calculations,
+    last_ping, status, created_at,
+    number_of_active_calculations
+FROM agents
+WHERE agent_id = $1
+`
+
+func (q *Queries) GetAgentByID(ctx context.Context, agentID int32) (Agent, error) {
+	row := q.db.QueryRowContext(ctx, getAgentByID, agentID)
+	var i Agent
+	err := row.Scan(
+		&i.AgentID,
+		&i.NumberOfParallelCalculations,
+		&i.LastPing,
+		&i.Status,
+		&i.CreatedAt,
+		&i.NumberOfActiveCalculations,
+	)
+	return i, err
+}
+
 const getAgents = `-- name: GetAgents :many
 SELECT
     agent_id, number_of_parallel_calculations,
@@ -8,7 +8,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const assignExpressionToAgent = `-- name: AssignExpressionToAgent :exec
@@ -29,13 +32,13 @@ func (q *Queries) AssignExpressionToAgent(ctx context.Context, arg AssignExpress
 
 const createExpression = `-- name: CreateExpression :one
 INSERT INTO expressions
-    (created_at, updated_at, data, parse_data, status, user_id)
+    (created_at, updated_at, data, parse_data, status, user_id, mode, bindings, deadline, batch_id)
 VALUES
-    ($1, $2, $3, $4, $5, $6)
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 `
 
 type CreateExpressionParams struct {
@@ -45,6 +48,10 @@ type CreateExpressionParams struct {
 	ParseData string
 	Status    ExpressionStatus
 	UserID    int32
+	Mode      EvaluationMode
+	Bindings  json.RawMessage
+	Deadline  sql.NullTime
+	BatchID   sql.NullInt32
 }
 
 func (q *Queries) CreateExpression(ctx context.Context, arg CreateExpressionParams) (Expression, error) {
@@ -55,6 +62,10 @@ func (q *Queries) CreateExpression(ctx context.Context, arg CreateExpressionPara
 		arg.ParseData,
 		arg.Status,
 		arg.UserID,
+		arg.Mode,
+		arg.Bindings,
+		arg.Deadline,
+		arg.BatchID,
 	)
 	var i Expression
 	err := row.Scan(
@@ -68,6 +79,11 @@ func (q *Queries) CreateExpression(ctx context.Context, arg CreateExpressionPara
 		&i.Status,
 		&i.Result,
 		&i.IsReady,
+		&i.Mode,
+		&i.Bindings,
+		&i.ErrorMessage,
+		&i.Deadline,
+		&i.BatchID,
 	)
 	return i, err
 }
@@ -76,7 +92,7 @@ const getComputingExpressions = `-- name: GetComputingExpressions :many
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE status IN ('ready_for_computation', 'computing', 'terminated')
 ORDER BY created_at DESC
@@ -102,6 +118,11 @@ func (q *Queries) GetComputingExpressions(ctx context.Context) ([]Expression, er
 			&i.Status,
 			&i.Result,
 			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
 		); err != nil {
 			return nil, err
 		}
@@ -120,7 +141,7 @@ const getExpressionByID = `-- name: GetExpressionByID :one
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE expression_id = $1
 `
@@ -139,6 +160,11 @@ func (q *Queries) GetExpressionByID(ctx context.Context, expressionID int32) (Ex
 		&i.Status,
 		&i.Result,
 		&i.IsReady,
+		&i.Mode,
+		&i.Bindings,
+		&i.ErrorMessage,
+		&i.Deadline,
+		&i.BatchID,
 	)
 	return i, err
 }
@@ -147,7 +173,7 @@ const getExpressionWithStatusComputing = `-- name: GetExpressionWithStatusComput
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE status = 'computing'
 ORDER BY created_at DESC
@@ -173,6 +199,11 @@ func (q *Queries) GetExpressionWithStatusComputing(ctx context.Context) ([]Expre
 			&i.Status,
 			&i.Result,
 			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
 		); err != nil {
 			return nil, err
 		}
@@ -191,7 +222,7 @@ const getExpressions = `-- name: GetExpressions :many
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE user_id = $1
 ORDER BY created_at DESC
@@ -217,6 +248,236 @@ func (q *Queries) GetExpressions(ctx context.Context, userID int32) ([]Expressio
 			&i.Status,
 			&i.Result,
 			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpressionsByCreatedAtAsc = `-- name: GetExpressionsByCreatedAtAsc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE ($1::int IS NULL OR user_id = $1)
+    AND (cardinality($2::text[]) = 0 OR status::text = ANY($2::text[]))
+    AND ($3::timestamp IS NULL OR created_at >= $3)
+    AND ($4::timestamp IS NULL OR created_at < $4)
+    AND ($5::text = '' OR data ILIKE '%' || $5 || '%')
+    AND ($6::timestamp IS NULL OR (created_at, expression_id) > ($6, $7::int))
+ORDER BY created_at ASC, expression_id ASC
+LIMIT $8
+`
+
+type GetExpressionsByCreatedAtAscParams struct {
+	UserID      sql.NullInt32
+	Statuses    []string
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	Search      string
+	CursorTime  sql.NullTime
+	CursorID    int32
+	PageSize    int32
+}
+
+func (q *Queries) GetExpressionsByCreatedAtAsc(ctx context.Context, arg GetExpressionsByCreatedAtAscParams) ([]Expression, error) {
+	rows, err := q.db.QueryContext(ctx, getExpressionsByCreatedAtAsc,
+		arg.UserID,
+		pq.Array(arg.Statuses),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Search,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Expression
+	for rows.Next() {
+		var i Expression
+		if err := rows.Scan(
+			&i.ExpressionID,
+			&i.UserID,
+			&i.AgentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Data,
+			&i.ParseData,
+			&i.Status,
+			&i.Result,
+			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpressionsByCreatedAtDesc = `-- name: GetExpressionsByCreatedAtDesc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE ($1::int IS NULL OR user_id = $1)
+    AND (cardinality($2::text[]) = 0 OR status::text = ANY($2::text[]))
+    AND ($3::timestamp IS NULL OR created_at >= $3)
+    AND ($4::timestamp IS NULL OR created_at < $4)
+    AND ($5::text = '' OR data ILIKE '%' || $5 || '%')
+    AND ($6::timestamp IS NULL OR (created_at, expression_id) < ($6, $7::int))
+ORDER BY created_at DESC, expression_id DESC
+LIMIT $8
+`
+
+type GetExpressionsByCreatedAtDescParams struct {
+	UserID      sql.NullInt32
+	Statuses    []string
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	Search      string
+	CursorTime  sql.NullTime
+	CursorID    int32
+	PageSize    int32
+}
+
+func (q *Queries) GetExpressionsByCreatedAtDesc(ctx context.Context, arg GetExpressionsByCreatedAtDescParams) ([]Expression, error) {
+	rows, err := q.db.QueryContext(ctx, getExpressionsByCreatedAtDesc,
+		arg.UserID,
+		pq.Array(arg.Statuses),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Search,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Expression
+	for rows.Next() {
+		var i Expression
+		if err := rows.Scan(
+			&i.ExpressionID,
+			&i.UserID,
+			&i.AgentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Data,
+			&i.ParseData,
+			&i.Status,
+			&i.Result,
+			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpressionsByUpdatedAtAsc = `-- name: GetExpressionsByUpdatedAtAsc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE ($1::int IS NULL OR user_id = $1)
+    AND (cardinality($2::text[]) = 0 OR status::text = ANY($2::text[]))
+    AND ($3::timestamp IS NULL OR created_at >= $3)
+    AND ($4::timestamp IS NULL OR created_at < $4)
+    AND ($5::text = '' OR data ILIKE '%' || $5 || '%')
+    AND ($6::timestamp IS NULL OR (updated_at, expression_id) > ($6, $7::int))
+ORDER BY updated_at ASC, expression_id ASC
+LIMIT $8
+`
+
+type GetExpressionsByUpdatedAtAscParams struct {
+	UserID      sql.NullInt32
+	Statuses    []string
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	Search      string
+	CursorTime  sql.NullTime
+	CursorID    int32
+	PageSize    int32
+}
+
+func (q *Queries) GetExpressionsByUpdatedAtAsc(ctx context.Context, arg GetExpressionsByUpdatedAtAscParams) ([]Expression, error) {
+	rows, err := q.db.QueryContext(ctx, getExpressionsByUpdatedAtAsc,
+		arg.UserID,
+		pq.Array(arg.Statuses),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Search,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Expression
+	for rows.Next() {
+		var i Expression
+		if err := rows.Scan(
+			&i.ExpressionID,
+			&i.UserID,
+			&i.AgentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Data,
+			&i.ParseData,
+			&i.Status,
+			&i.Result,
+			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
 		); err != nil {
 			return nil, err
 		}
@@ -231,11 +492,115 @@ func (q *Queries) GetExpressions(ctx context.Context, userID int32) ([]Expressio
 	return items, nil
 }
 
+const getExpressionsByUpdatedAtDesc = `-- name: GetExpressionsByUpdatedAtDesc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE ($1::int IS NULL OR user_id = $1)
+    AND (cardinality($2::text[]) = 0 OR status::text = ANY($2::text[]))
+    AND ($3::timestamp IS NULL OR created_at >= $3)
+    AND ($4::timestamp IS NULL OR created_at < $4)
+    AND ($5::text = '' OR data ILIKE '%' || $5 || '%')
+    AND ($6::timestamp IS NULL OR (updated_at, expression_id) < ($6, $7::int))
+ORDER BY updated_at DESC, expression_id DESC
+LIMIT $8
+`
+
+type GetExpressionsByUpdatedAtDescParams struct {
+	UserID      sql.NullInt32
+	Statuses    []string
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	Search      string
+	CursorTime  sql.NullTime
+	CursorID    int32
+	PageSize    int32
+}
+
+func (q *Queries) GetExpressionsByUpdatedAtDesc(ctx context.Context, arg GetExpressionsByUpdatedAtDescParams) ([]Expression, error) {
+	rows, err := q.db.QueryContext(ctx, getExpressionsByUpdatedAtDesc,
+		arg.UserID,
+		pq.Array(arg.Statuses),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.Search,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Expression
+	for rows.Next() {
+		var i Expression
+		if err := rows.Scan(
+			&i.ExpressionID,
+			&i.UserID,
+			&i.AgentID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Data,
+			&i.ParseData,
+			&i.Status,
+			&i.Result,
+			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFinishedExpressionIDs = `-- name: GetFinishedExpressionIDs :many
+SELECT expression_id
+FROM expressions
+WHERE expression_id = ANY($1::int[]) AND status IN ('result', 'overflow', 'error', 'cancelled', 'timed_out')
+`
+
+func (q *Queries) GetFinishedExpressionIDs(ctx context.Context, dollar_1 []int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, getFinishedExpressionIDs, pq.Array(dollar_1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var expression_id int32
+		if err := rows.Scan(&expression_id); err != nil {
+			return nil, err
+		}
+		items = append(items, expression_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTerminatedExpressions = `-- name: GetTerminatedExpressions :many
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE status = 'terminated'
 ORDER BY created_at DESC
@@ -261,6 +626,11 @@ func (q *Queries) GetTerminatedExpressions(ctx context.Context) ([]Expression, e
 			&i.Status,
 			&i.Result,
 			&i.IsReady,
+			&i.Mode,
+			&i.Bindings,
+			&i.ErrorMessage,
+			&i.Deadline,
+			&i.BatchID,
 		); err != nil {
 			return nil, err
 		}
@@ -275,15 +645,35 @@ func (q *Queries) GetTerminatedExpressions(ctx context.Context) ([]Expression, e
 	return items, nil
 }
 
+const makeExpressionCancelled = `-- name: MakeExpressionCancelled :execrows
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'cancelled'
+WHERE expression_id = $2 AND user_id = $3 AND status IN ('ready_for_computation', 'computing', 'terminated')
+`
+
+type MakeExpressionCancelledParams struct {
+	UpdatedAt    time.Time
+	ExpressionID int32
+	UserID       int32
+}
+
+func (q *Queries) MakeExpressionCancelled(ctx context.Context, arg MakeExpressionCancelledParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, makeExpressionCancelled, arg.UpdatedAt, arg.ExpressionID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const makeExpressionReady = `-- name: MakeExpressionReady :exec
 UPDATE expressions
 SET parse_data = $1, result = $2, updated_at = $3, is_ready = True, status = 'result'
-WHERE expression_id = $4
+WHERE expression_id = $4 AND status IN ('ready_for_computation', 'computing', 'terminated')
 `
 
 type MakeExpressionReadyParams struct {
 	ParseData    string
-	Result       int32
+	Result       string
 	UpdatedAt    time.Time
 	ExpressionID int32
 }
@@ -298,10 +688,46 @@ func (q *Queries) MakeExpressionReady(ctx context.Context, arg MakeExpressionRea
 	return err
 }
 
+const makeExpressionError = `-- name: MakeExpressionError :exec
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'error', error_message = $2
+WHERE expression_id = $3 AND status IN ('ready_for_computation', 'computing', 'terminated')
+`
+
+type MakeExpressionErrorParams struct {
+	UpdatedAt    time.Time
+	ErrorMessage string
+	ExpressionID int32
+}
+
+func (q *Queries) MakeExpressionError(ctx context.Context, arg MakeExpressionErrorParams) error {
+	_, err := q.db.ExecContext(ctx, makeExpressionError, arg.UpdatedAt, arg.ErrorMessage, arg.ExpressionID)
+	return err
+}
+
+const makeExpressionOverflow = `-- name: MakeExpressionOverflow :execrows
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'overflow'
+WHERE expression_id = $2 AND status IN ('ready_for_computation', 'computing', 'terminated')
+`
+
+type MakeExpressionOverflowParams struct {
+	UpdatedAt    time.Time
+	ExpressionID int32
+}
+
+func (q *Queries) MakeExpressionOverflow(ctx context.Context, arg MakeExpressionOverflowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, makeExpressionOverflow, arg.UpdatedAt, arg.ExpressionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const makeExpressionsTerminated = `-- name: MakeExpressionsTerminated :exec
 UPDATE expressions
 SET status = 'terminated'
-WHERE agent_id = $1 AND is_ready = false
+WHERE agent_id = $1 AND status IN ('ready_for_computation', 'computing')
 `
 
 func (q *Queries) MakeExpressionsTerminated(ctx context.Context, agentID sql.NullInt32) error {
@@ -309,6 +735,36 @@ func (q *Queries) MakeExpressionsTerminated(ctx context.Context, agentID sql.Nul
 	return err
 }
 
+const makeExpressionsTimedOut = `-- name: MakeExpressionsTimedOut :many
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'timed_out', error_message = 'deadline exceeded'
+WHERE deadline < $1 AND status IN ('ready_for_computation', 'computing', 'terminated')
+RETURNING expression_id
+`
+
+func (q *Queries) MakeExpressionsTimedOut(ctx context.Context, updatedAt time.Time) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, makeExpressionsTimedOut, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var expression_id int32
+		if err := rows.Scan(&expression_id); err != nil {
+			return nil, err
+		}
+		items = append(items, expression_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateExpressionParseData = `-- name: UpdateExpressionParseData :exec
 UPDATE expressions
 SET parse_data = $1
@@ -328,7 +784,7 @@ func (q *Queries) UpdateExpressionParseData(ctx context.Context, arg UpdateExpre
 const updateExpressionStatus = `-- name: UpdateExpressionStatus :exec
 UPDATE expressions
 SET status = $1
-WHERE expression_id = $2
+WHERE expression_id = $2 AND status IN ('ready_for_computation', 'computing', 'terminated')
 `
 
 type UpdateExpressionStatusParams struct {
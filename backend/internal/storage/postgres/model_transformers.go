@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -14,8 +15,13 @@ type ExpressionTransformed struct {
 	Data         string           `json:"data"`
 	ParseData    string           `json:"parse_data"`
 	Status       ExpressionStatus `json:"status"`
-	Result       int32            `json:"result"`
+	Result       string           `json:"result"`
 	IsReady      bool             `json:"is_ready"`
+	Mode         EvaluationMode   `json:"mode"`
+	Bindings     json.RawMessage  `json:"bindings"`
+	ErrorMessage string           `json:"error_message"`
+	Deadline     sql.NullTime     `json:"deadline"`
+	BatchID      sql.NullInt32    `json:"batch_id"`
 }
 
 func DatabaseExpressionToExpression(dbExpr Expression) ExpressionTransformed {
@@ -30,6 +36,63 @@ func DatabaseExpressionsToExpressions(dbExprs []Expression) []ExpressionTransfor
 	return exprs
 }
 
+type ExpressionStepTransformed struct {
+	StepID       int32         `json:"step_id"`
+	ExpressionID int32         `json:"expression_id"`
+	NodeID       int32         `json:"node_id"`
+	Token        string        `json:"token"`
+	AgentID      sql.NullInt32 `json:"agent_id"`
+	Result       string        `json:"result"`
+	ErrorMessage string        `json:"error_message"`
+	DispatchedAt time.Time     `json:"dispatched_at"`
+	ComputedAt   sql.NullTime  `json:"computed_at"`
+}
+
+func DatabaseExpressionStepToExpressionStep(dbStep ExpressionStep) ExpressionStepTransformed {
+	return ExpressionStepTransformed(dbStep)
+}
+
+func DatabaseExpressionStepsToExpressionSteps(dbSteps []ExpressionStep) []ExpressionStepTransformed {
+	steps := []ExpressionStepTransformed{}
+	for _, dbStep := range dbSteps {
+		steps = append(steps, DatabaseExpressionStepToExpressionStep(dbStep))
+	}
+	return steps
+}
+
+// ExpressionWithStepsTransformed is the expression with the trace of its computation.
+type ExpressionWithStepsTransformed struct {
+	ExpressionTransformed
+	Steps []ExpressionStepTransformed `json:"steps"`
+}
+
+type BatchTransformed struct {
+	BatchID   int32     `json:"batch_id"`
+	UserID    int32     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Total     int64     `json:"total"`
+	Completed int64     `json:"completed"`
+	Failed    int64     `json:"failed"`
+	Progress  float64   `json:"progress"`
+}
+
+// DatabaseBatchToBatch returns the batch with the number of finished expressions,
+// progress is the fraction of finished ones, both completed and failed.
+func DatabaseBatchToBatch(dbBatch Batch, progress GetBatchProgressRow) BatchTransformed {
+	batch := BatchTransformed{
+		BatchID:   dbBatch.BatchID,
+		UserID:    dbBatch.UserID,
+		CreatedAt: dbBatch.CreatedAt,
+		Total:     progress.Total,
+		Completed: progress.Completed,
+		Failed:    progress.Failed,
+	}
+	if progress.Total != 0 {
+		batch.Progress = float64(progress.Completed+progress.Failed) / float64(progress.Total)
+	}
+	return batch
+}
+
 type OperationTransformed struct {
 	OperationID   int32  `json:"operation_id"`
 	OperationType string `json:"operation_type"`
@@ -49,6 +112,23 @@ func DatabaseOperationsToOperations(dbOpers []Operation) []OperationTransformed
 	return opers
 }
 
+type DefaultOperationTransformed struct {
+	OperationType string `json:"operation_type"`
+	ExecutionTime int32  `json:"execution_time"`
+}
+
+func DatabaseDefaultOperationToDefaultOperation(dbOper DefaultOperation) DefaultOperationTransformed {
+	return DefaultOperationTransformed(dbOper)
+}
+
+func DatabaseDefaultOperationsToDefaultOperations(dbOpers []DefaultOperation) []DefaultOperationTransformed {
+	opers := []DefaultOperationTransformed{}
+	for _, dbOper := range dbOpers {
+		opers = append(opers, DatabaseDefaultOperationToDefaultOperation(dbOper))
+	}
+	return opers
+}
+
 type AgentTransformed struct {
 	AgentID                      int32       `json:"agent_id"`
 	NumberOfParallelCalculations int32       `json:"number_of_parallel_calculations"`
@@ -71,9 +151,10 @@ func DatabaseAgentsToAgents(dbAgents []Agent) []AgentTransformed {
 }
 
 type UserTransformed struct {
-	UserID       int32  `json:"user_id"`
-	Email        string `json:"email"`
-	PasswordHash []byte `json:"password_hash"`
+	UserID       int32    `json:"user_id"`
+	Email        string   `json:"email"`
+	PasswordHash []byte   `json:"-"`
+	Roles        []string `json:"roles"`
 }
 
 func DatabaseUserToUser(dbUser User) UserTransformed {
@@ -87,3 +168,46 @@ func DatabaseUsersToUsers(dbUsers []User) []UserTransformed {
 	}
 	return users
 }
+
+type TemplateTransformed struct {
+	TemplateID int32     `json:"template_id"`
+	UserID     int32     `json:"user_id"`
+	Name       string    `json:"name"`
+	Data       string    `json:"data"`
+	ParseData  string    `json:"parse_data"`
+	Variables  []string  `json:"variables"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func DatabaseTemplateToTemplate(dbTemplate Template) TemplateTransformed {
+	return TemplateTransformed(dbTemplate)
+}
+
+func DatabaseTemplatesToTemplates(dbTemplates []Template) []TemplateTransformed {
+	templates := []TemplateTransformed{}
+	for _, dbTemplate := range dbTemplates {
+		templates = append(templates, DatabaseTemplateToTemplate(dbTemplate))
+	}
+	return templates
+}
+
+type DeadLetterTransformed struct {
+	DeadLetterID int32     `json:"dead_letter_id"`
+	Queue        string    `json:"queue"`
+	Body         string    `json:"body"`
+	Reason       string    `json:"reason"`
+	Retries      int32     `json:"retries"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func DatabaseDeadLetterToDeadLetter(dbDeadLetter DeadLetter) DeadLetterTransformed {
+	return DeadLetterTransformed(dbDeadLetter)
+}
+
+func DatabaseDeadLettersToDeadLetters(dbDeadLetters []DeadLetter) []DeadLetterTransformed {
+	deadLetters := []DeadLetterTransformed{}
+	for _, dbDeadLetter := range dbDeadLetters {
+		deadLetters = append(deadLetters, DatabaseDeadLetterToDeadLetter(dbDeadLetter))
+	}
+	return deadLetters
+}
@@ -7,6 +7,7 @@ package postgres
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -55,6 +56,48 @@ func (ns NullAgentStatus) Value() (driver.Value, error) {
 	return string(ns.AgentStatus), nil
 }
 
+type EvaluationMode string
+
+const (
+	EvaluationModeStandard  EvaluationMode = "standard"
+	EvaluationModeArbitrary EvaluationMode = "arbitrary"
+)
+
+func (e *EvaluationMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EvaluationMode(s)
+	case string:
+		*e = EvaluationMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EvaluationMode: %T", src)
+	}
+	return nil
+}
+
+type NullEvaluationMode struct {
+	EvaluationMode EvaluationMode
+	Valid          bool // Valid is true if EvaluationMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEvaluationMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.EvaluationMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EvaluationMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEvaluationMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EvaluationMode), nil
+}
+
 type ExpressionStatus string
 
 const (
@@ -62,6 +105,10 @@ const (
 	ExpressionStatusComputing           ExpressionStatus = "computing"
 	ExpressionStatusResult              ExpressionStatus = "result"
 	ExpressionStatusTerminated          ExpressionStatus = "terminated"
+	ExpressionStatusOverflow            ExpressionStatus = "overflow"
+	ExpressionStatusError               ExpressionStatus = "error"
+	ExpressionStatusCancelled           ExpressionStatus = "cancelled"
+	ExpressionStatusTimedOut            ExpressionStatus = "timed_out"
 )
 
 func (e *ExpressionStatus) Scan(src interface{}) error {
@@ -99,6 +146,49 @@ func (ns NullExpressionStatus) Value() (driver.Value, error) {
 	return string(ns.ExpressionStatus), nil
 }
 
+type NodeStatus string
+
+const (
+	NodeStatusWaiting NodeStatus = "waiting"
+	NodeStatusReady   NodeStatus = "ready"
+	NodeStatusDone    NodeStatus = "done"
+)
+
+func (e *NodeStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NodeStatus(s)
+	case string:
+		*e = NodeStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NodeStatus: %T", src)
+	}
+	return nil
+}
+
+type NullNodeStatus struct {
+	NodeStatus NodeStatus
+	Valid      bool // Valid is true if NodeStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNodeStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.NodeStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NodeStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNodeStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NodeStatus), nil
+}
+
 type Agent struct {
 	AgentID                      int32
 	NumberOfParallelCalculations int32
@@ -108,6 +198,26 @@ type Agent struct {
 	NumberOfActiveCalculations   int32
 }
 
+type Batch struct {
+	BatchID   int32
+	UserID    int32
+	CreatedAt time.Time
+}
+
+type DeadLetter struct {
+	DeadLetterID int32
+	Queue        string
+	Body         string
+	Reason       string
+	Retries      int32
+	CreatedAt    time.Time
+}
+
+type DefaultOperation struct {
+	OperationType string
+	ExecutionTime int32
+}
+
 type Expression struct {
 	ExpressionID int32
 	UserID       int32
@@ -117,8 +227,44 @@ type Expression struct {
 	Data         string
 	ParseData    string
 	Status       ExpressionStatus
-	Result       int32
+	Result       string
 	IsReady      bool
+	Mode         EvaluationMode
+	Bindings     json.RawMessage
+	ErrorMessage string
+	Deadline     sql.NullTime
+	BatchID      sql.NullInt32
+}
+
+type ExpressionNode struct {
+	ExpressionID int32
+	NodeID       int32
+	ParentID     sql.NullInt32
+	OperandIndex int32
+	Operator     string
+	Value        string
+	Status       NodeStatus
+}
+
+type ExpressionStep struct {
+	StepID       int32
+	ExpressionID int32
+	NodeID       int32
+	Token        string
+	AgentID      sql.NullInt32
+	Result       string
+	ErrorMessage string
+	DispatchedAt time.Time
+	ComputedAt   sql.NullTime
+}
+
+type IdempotencyKey struct {
+	UserID       int32
+	Key          string
+	RequestHash  string
+	ExpressionID int32
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
 }
 
 type Operation struct {
@@ -128,8 +274,34 @@ type Operation struct {
 	UserID        int32
 }
 
+type RefreshToken struct {
+	TokenHash string
+	FamilyID  string
+	UserID    int32
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt sql.NullTime
+	RevokedAt sql.NullTime
+}
+
+type RevokedToken struct {
+	Jti       string
+	ExpiresAt time.Time
+}
+
+type Template struct {
+	TemplateID int32
+	UserID     int32
+	Name       string
+	Data       string
+	ParseData  string
+	Variables  []string
+	CreatedAt  time.Time
+}
+
 type User struct {
 	UserID       int32
 	Email        string
 	PasswordHash []byte
+	Roles        []string
 }
@@ -9,6 +9,36 @@ import (
 	"context"
 )
 
+const getDefaultOperations = `-- name: GetDefaultOperations :many
+SELECT
+    operation_type, execution_time
+FROM default_operations
+ORDER BY operation_type DESC
+`
+
+func (q *Queries) GetDefaultOperations(ctx context.Context) ([]DefaultOperation, error) {
+	rows, err := q.db.QueryContext(ctx, getDefaultOperations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DefaultOperation
+	for rows.Next() {
+		var i DefaultOperation
+		if err := rows.Scan(&i.OperationType, &i.ExecutionTime); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getOperationTimeByType = `-- name: GetOperationTimeByType :one
 SELECT execution_time
 FROM operations
@@ -64,11 +94,11 @@ func (q *Queries) GetOperations(ctx context.Context, userID int32) ([]Operation,
 }
 
 const newOperationsForUser = `-- name: NewOperationsForUser :exec
-INSERT INTO operations (operation_type, user_id) VALUES 
-('+', $1),
-('-', $1),
-('*', $1),
-('/', $1)
+INSERT INTO operations
+    (operation_type, execution_time, user_id)
+SELECT
+    operation_type, execution_time, $1
+FROM default_operations
 `
 
 func (q *Queries) NewOperationsForUser(ctx context.Context, userID int32) error {
@@ -76,6 +106,25 @@ func (q *Queries) NewOperationsForUser(ctx context.Context, userID int32) error
 	return err
 }
 
+const updateDefaultOperationTime = `-- name: UpdateDefaultOperationTime :one
+UPDATE default_operations
+SET execution_time = $1
+WHERE operation_type = $2
+RETURNING operation_type, execution_time
+`
+
+type UpdateDefaultOperationTimeParams struct {
+	ExecutionTime int32
+	OperationType string
+}
+
+func (q *Queries) UpdateDefaultOperationTime(ctx context.Context, arg UpdateDefaultOperationTimeParams) (DefaultOperation, error) {
+	row := q.db.QueryRowContext(ctx, updateDefaultOperationTime, arg.ExecutionTime, arg.OperationType)
+	var i DefaultOperation
+	err := row.Scan(&i.OperationType, &i.ExecutionTime)
+	return i, err
+}
+
 const updateOperationTime = `-- name: UpdateOperationTime :one
 UPDATE operations
 SET execution_time = $1
@@ -100,3 +149,19 @@ func (q *Queries) UpdateOperationTime(ctx context.Context, arg UpdateOperationTi
 	)
 	return i, err
 }
+
+const updateOperationTimeForAllUsers = `-- name: UpdateOperationTimeForAllUsers :exec
+UPDATE operations
+SET execution_time = $1
+WHERE operation_type = $2
+`
+
+type UpdateOperationTimeForAllUsersParams struct {
+	ExecutionTime int32
+	OperationType string
+}
+
+func (q *Queries) UpdateOperationTimeForAllUsers(ctx context.Context, arg UpdateOperationTimeForAllUsersParams) error {
+	_, err := q.db.ExecContext(ctx, updateOperationTimeForAllUsers, arg.ExecutionTime, arg.OperationType)
+	return err
+}
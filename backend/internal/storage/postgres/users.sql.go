@@ -7,10 +7,12 @@ package postgres
 
 import (
 	"context"
+
+	"github.com/lib/pq"
 )
 
 const getUser = `-- name: GetUser :one
-SELECT user_id, email, password_hash
+SELECT user_id, email, password_hash, roles
 FROM users
 WHERE email = $1
 `
@@ -18,26 +20,108 @@ WHERE email = $1
 func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 	row := q.db.QueryRowContext(ctx, getUser, email)
 	var i User
-	err := row.Scan(&i.UserID, &i.Email, &i.PasswordHash)
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.PasswordHash,
+		pq.Array(&i.Roles),
+	)
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT user_id, email, password_hash, roles
+FROM users
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, userID int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, userID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.PasswordHash,
+		pq.Array(&i.Roles),
+	)
+	return i, err
+}
+
+const getUsers = `-- name: GetUsers :many
+SELECT user_id, email, password_hash, roles
+FROM users
+ORDER BY user_id
+`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.PasswordHash,
+			pq.Array(&i.Roles),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const saveUser = `-- name: SaveUser :one
 INSERT INTO users
-    (email, password_hash)
+    (email, password_hash, roles)
 VALUES
-    ($1, $2)
+    ($1, $2, $3)
 RETURNING user_id
 `
 
 type SaveUserParams struct {
 	Email        string
 	PasswordHash []byte
+	Roles        []string
 }
 
 func (q *Queries) SaveUser(ctx context.Context, arg SaveUserParams) (int32, error) {
-	row := q.db.QueryRowContext(ctx, saveUser, arg.Email, arg.PasswordHash)
+	row := q.db.QueryRowContext(ctx, saveUser, arg.Email, arg.PasswordHash, pq.Array(arg.Roles))
 	var user_id int32
 	err := row.Scan(&user_id)
 	return user_id, err
 }
+
+const updateUserRoles = `-- name: UpdateUserRoles :one
+UPDATE users
+SET roles = $1
+WHERE user_id = $2
+RETURNING user_id, email, password_hash, roles
+`
+
+type UpdateUserRolesParams struct {
+	Roles  []string
+	UserID int32
+}
+
+func (q *Queries) UpdateUserRoles(ctx context.Context, arg UpdateUserRolesParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRoles, pq.Array(arg.Roles), arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.PasswordHash,
+		pq.Array(&i.Roles),
+	)
+	return i, err
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/Prrromanssss/DAEC-fullstack/internal/lib/logger/sl"
 	"github.com/Prrromanssss/DAEC-fullstack/internal/storage/postgres"
@@ -16,6 +18,9 @@ var (
 	ErrUserExists   = errors.New("user already exists")
 	ErrUserNotFound = errors.New("user not found")
 	ErrAppNotFound  = errors.New("app not found")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token reused")
 )
 
 type Storage struct {
@@ -48,11 +53,12 @@ func NewStorage(log *slog.Logger, dbURL string) *Storage {
 	}
 }
 
-// SaveUser saves user to storage.
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int32, error) {
+// SaveUser saves user with the roles to storage.
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte, roles []string) (int32, error) {
 	userID, err := s.Queries.SaveUser(ctx, postgres.SaveUserParams{
 		Email:        email,
 		PasswordHash: passHash,
+		Roles:        roles,
 	})
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "users_email_key" {
@@ -74,3 +80,144 @@ func (s *Storage) User(ctx context.Context, email string) (postgres.User, error)
 
 	return user, nil
 }
+
+// UserByID gets user from storage by its ID.
+func (s *Storage) UserByID(ctx context.Context, userID int32) (postgres.User, error) {
+	user, err := s.Queries.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return postgres.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return postgres.User{}, err
+	}
+
+	return user, nil
+}
+
+// SaveRefreshToken saves new refresh token to storage.
+func (s *Storage) SaveRefreshToken(ctx context.Context, token postgres.CreateRefreshTokenParams) error {
+	_, err := s.Queries.CreateRefreshToken(ctx, token)
+	return err
+}
+
+// RotateRefreshToken replaces the refresh token with the next token of the same family.
+//
+// If the token has already been rotated, somebody reuses it,
+// so the whole family is revoked and ErrRefreshTokenReused is returned.
+func (s *Storage) RotateRefreshToken(
+	ctx context.Context,
+	tokenHash string,
+	next postgres.CreateRefreshTokenParams,
+) (postgres.RefreshToken, error) {
+	const fn = "storage.RotateRefreshToken"
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return postgres.RefreshToken{}, fmt.Errorf("can't begin transaction: %v, fn: %s", err, fn)
+	}
+
+	rollback := func(err error) (postgres.RefreshToken, error) {
+		errRollback := tx.Rollback()
+		if errRollback != nil {
+			return postgres.RefreshToken{}, fmt.Errorf("can't rollback transaction: %v, fn: %s", errRollback, fn)
+		}
+		return postgres.RefreshToken{}, err
+	}
+
+	qtx := s.Queries.WithTx(tx)
+
+	token, err := qtx.GetRefreshTokenForUpdate(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rollback(ErrRefreshTokenNotFound)
+	}
+	if err != nil {
+		return rollback(fmt.Errorf("can't get refresh token: %v, fn: %s", err, fn))
+	}
+
+	if token.RevokedAt.Valid || !token.ExpiresAt.After(next.CreatedAt) {
+		return rollback(ErrRefreshTokenNotFound)
+	}
+
+	if token.RotatedAt.Valid {
+		err = qtx.RevokeRefreshTokenFamily(ctx, postgres.RevokeRefreshTokenFamilyParams{
+			RevokedAt: sql.NullTime{Time: next.CreatedAt, Valid: true},
+			FamilyID:  token.FamilyID,
+		})
+		if err != nil {
+			return rollback(fmt.Errorf("can't revoke refresh tokens: %v, fn: %s", err, fn))
+		}
+
+		if err = tx.Commit(); err != nil {
+			return postgres.RefreshToken{}, fmt.Errorf("can't commit transaction: %v, fn: %s", err, fn)
+		}
+
+		return postgres.RefreshToken{}, ErrRefreshTokenReused
+	}
+
+	err = qtx.MakeRefreshTokenRotated(ctx, postgres.MakeRefreshTokenRotatedParams{
+		RotatedAt: sql.NullTime{Time: next.CreatedAt, Valid: true},
+		TokenHash: token.TokenHash,
+	})
+	if err != nil {
+		return rollback(fmt.Errorf("can't rotate refresh token: %v, fn: %s", err, fn))
+	}
+
+	next.FamilyID = token.FamilyID
+	next.UserID = token.UserID
+
+	newToken, err := qtx.CreateRefreshToken(ctx, next)
+	if err != nil {
+		return rollback(fmt.Errorf("can't create refresh token: %v, fn: %s", err, fn))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return postgres.RefreshToken{}, fmt.Errorf("can't commit transaction: %v, fn: %s", err, fn)
+	}
+
+	return newToken, nil
+}
+
+// RevokeRefreshToken revokes the whole family of the user's refresh token.
+func (s *Storage) RevokeRefreshToken(ctx context.Context, tokenHash string, userID int32) error {
+	revoked, err := s.Queries.RevokeUserRefreshToken(ctx, postgres.RevokeUserRefreshTokenParams{
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		TokenHash: tokenHash,
+		UserID:    userID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrRefreshTokenNotFound
+	}
+
+	return nil
+}
+
+// RevokeToken saves jti of the access token, so it isn't accepted till it expires.
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return s.Queries.RevokeToken(ctx, postgres.RevokeTokenParams{
+		Jti:       jti,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// IsTokenRevoked checks if the access token with given jti is revoked.
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.Queries.IsTokenRevoked(ctx, jti)
+}
+
+// DeleteExpiredTokens deletes refresh tokens and revoked access tokens which have expired.
+func (s *Storage) DeleteExpiredTokens(ctx context.Context, now time.Time) error {
+	const fn = "storage.DeleteExpiredTokens"
+
+	if err := s.Queries.DeleteExpiredRefreshTokens(ctx, now); err != nil {
+		return fmt.Errorf("can't delete expired refresh tokens: %v, fn: %s", err, fn)
+	}
+
+	if err := s.Queries.DeleteExpiredRevokedTokens(ctx, now); err != nil {
+		return fmt.Errorf("can't delete expired revoked tokens: %v, fn: %s", err, fn)
+	}
+
+	return nil
+}
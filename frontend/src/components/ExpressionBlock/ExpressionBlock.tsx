@@ -1,6 +1,7 @@
 import styles from "./ExpressionBlock.module.css";
 import { EXPRESSION_DESCRIPTION, ICONS } from "src/ts/consts";
 import { ExpressionBlockProps } from "src/ts/interfaces";
+import { EXPRESSION_STATUS } from "src/ts/enums";
 
 export const ExpressionBlock = ({ expression }: ExpressionBlockProps) => {
   const date = new Date(expression.created_at).toLocaleString();
@@ -16,9 +17,24 @@ export const ExpressionBlock = ({ expression }: ExpressionBlockProps) => {
           {expression.data} = {expression.is_ready ? expression.result : "?"} ({EXPRESSION_DESCRIPTION[expression.status]})
         </p>
       </div>
+      {expression.status === EXPRESSION_STATUS.COMPUTING && expression.progress !== undefined && (
+        <p className={styles.createdAt}>
+          Progress: {Math.round(expression.progress * 100)}%
+        </p>
+      )}
+      {expression.status === EXPRESSION_STATUS.ERROR && (
+        <p className={styles.createdAt}>
+          Error: {expression.error_message}
+        </p>
+      )}
       <p className={styles.createdAt}>
         Created at: {date}
       </p>
+      {expression.deadline.Valid && (
+        <p className={styles.createdAt}>
+          Deadline: {new Date(expression.deadline.Time).toLocaleString()}
+        </p>
+      )}
     </div>
   )
 }
\ No newline at end of file
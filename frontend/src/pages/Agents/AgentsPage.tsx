@@ -3,13 +3,22 @@ import { useEffect, useState } from "react";
 import { Agent } from "src/ts/interfaces";
 import { getAgents } from "src/services/api";
 import { AgentBlock } from "src/components/AgentBlock/AgentBlock";
+import { toast } from 'react-toastify';
 
 export const AgentsPage = () => {
   const [agents, setAgents] = useState<Agent[]>([]);
 
   useEffect(() => {
     getAgents()
-      .then(data => setAgents(data));
+      .then(data => setAgents(data))
+      .catch(err => {
+        // Only admins can see agents.
+        if (err.response?.status === 403) {
+          toast.error("Only admins can see agents");
+          return;
+        }
+        toast.error(err.response?.data?.error ?? err.message);
+      });
   }, []);
 
   return (
@@ -3,7 +3,7 @@ import { useEffect, useState } from "react";
 import { Expression } from "src/ts/interfaces";
 import { Button } from "src/components/Button/Button";
 import { Input } from "src/components/Input/Input";
-import { createExpression, getExpressions } from "src/services/api";
+import { createExpression, getExpressions, subscribeToExpressions } from "src/services/api";
 import { ExpressionBlock } from "src/components/ExpressionBlock/ExpressionBlock";
 import { toast } from 'react-toastify';
 
@@ -32,6 +32,16 @@ export const ExpressionsPage = () => {
       .catch(err => {
         toast.error(err.response.data.error);
       });
+
+    const events = subscribeToExpressions(expression => {
+      setExpressions(expressions => {
+        if (!expressions.some(item => item.expression_id === expression.expression_id)) {
+          return [expression, ...expressions];
+        }
+        return expressions.map(item => item.expression_id === expression.expression_id ? expression : item);
+      });
+    });
+    return () => events.close();
   }, []);
 
   return (
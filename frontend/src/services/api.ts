@@ -8,6 +8,13 @@ export const getExpressions = async (): Promise<Expression[]> => {
   return data;
 }
 
+export const subscribeToExpressions = (onExpression: (expression: Expression) => void): EventSource => {
+  const token = sessionStorage.getItem("token") || "";
+  const events = new EventSource(`${axios.defaults.baseURL}/events?access_token=${encodeURIComponent(token)}`);
+  events.addEventListener("expression", (event: MessageEvent) => onExpression(JSON.parse(event.data)));
+  return events;
+}
+
 export const createExpression = async (name: string): Promise<Expression> => {
   const { data } = await axios.post("/expressions", { data: name });
   return data;
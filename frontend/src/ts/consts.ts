@@ -13,6 +13,10 @@ export const ICONS = {
   [EXPRESSION_STATUS.READY_FOR_COMPUTATION]: yellowIcon,
   [EXPRESSION_STATUS.RESULT]: greenIcon,
   [EXPRESSION_STATUS.COMPUTING]: blackIcon,
+  [EXPRESSION_STATUS.OVERFLOW]: redIcon,
+  [EXPRESSION_STATUS.ERROR]: redIcon,
+  [EXPRESSION_STATUS.CANCELLED]: redIcon,
+  [EXPRESSION_STATUS.TIMED_OUT]: redIcon,
 } as const;
 
 export const AGENT_DESCRIPTION = {
@@ -27,4 +31,8 @@ export const EXPRESSION_DESCRIPTION = {
   [EXPRESSION_STATUS.RESULT]: "the expression is ready",
   [EXPRESSION_STATUS.COMPUTING]: "the expression is being processed, it will be calculated soon",
   [EXPRESSION_STATUS.TERMINATED]: "agent was terminated",
+  [EXPRESSION_STATUS.OVERFLOW]: "the result is too big",
+  [EXPRESSION_STATUS.ERROR]: "the expression can't be calculated",
+  [EXPRESSION_STATUS.CANCELLED]: "the expression was cancelled",
+  [EXPRESSION_STATUS.TIMED_OUT]: "the expression wasn't calculated before its deadline",
 } as const;
\ No newline at end of file
@@ -10,6 +10,10 @@ export enum EXPRESSION_STATUS {
   COMPUTING = "computing",
   RESULT = "result",
   TERMINATED = "terminated",
+  OVERFLOW = "overflow",
+  ERROR = "error",
+  CANCELLED = "cancelled",
+  TIMED_OUT = "timed_out",
 }
 
 export enum AGENT_STATUS {
@@ -8,7 +8,10 @@ export interface Expression {
   data: string,
   status: EXPRESSION_STATUS,
   is_ready: boolean,
-  result: number,
+  result: string,
+  error_message: string,
+  deadline: { Time: string, Valid: boolean },
+  progress?: number,
   parse_data: string,
   user_id: number,
   agent_id: number,
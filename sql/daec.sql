@@ -57,4 +57,190 @@ CREATE TABLE IF NOT EXISTS operations (
 	  ON DELETE CASCADE
 );
 
-ALTER TABLE agents ADD COLUMN number_of_active_calculations int NOT NULL DEFAULT 0;
\ No newline at end of file
+ALTER TABLE agents ADD COLUMN number_of_active_calculations int NOT NULL DEFAULT 0;
+DROP TYPE IF EXISTS node_status;
+CREATE TYPE node_status AS ENUM ('waiting', 'ready', 'done');
+
+CREATE TABLE IF NOT EXISTS expression_nodes (
+    expression_id int NOT NULL,
+    node_id int NOT NULL,
+    parent_id int,
+    operand_index int NOT NULL DEFAULT 0,
+    operator text NOT NULL DEFAULT '',
+    value text NOT NULL DEFAULT '',
+    status node_status NOT NULL,
+
+    PRIMARY KEY(expression_id, node_id),
+    FOREIGN KEY(expression_id)
+      REFERENCES expressions(expression_id)
+      ON DELETE CASCADE
+);
+
+ALTER TABLE expressions ALTER COLUMN result TYPE text;
+ALTER TABLE expressions ALTER COLUMN result SET DEFAULT '0';
+
+DROP TYPE IF EXISTS evaluation_mode;
+CREATE TYPE evaluation_mode AS ENUM ('standard', 'arbitrary');
+
+ALTER TABLE expressions ADD COLUMN mode evaluation_mode NOT NULL DEFAULT 'standard';
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'overflow';
+
+ALTER TABLE operations ALTER COLUMN operation_type TYPE text;
+
+ALTER TABLE expressions ADD COLUMN bindings jsonb NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS templates (
+    template_id int GENERATED ALWAYS AS IDENTITY,
+    user_id int NOT NULL,
+    name text NOT NULL,
+    data text NOT NULL,
+    parse_data text NOT NULL,
+    variables text[] NOT NULL DEFAULT '{}',
+    created_at timestamp NOT NULL,
+
+    PRIMARY KEY(template_id),
+    CONSTRAINT template_name_user_id UNIQUE(name, user_id),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE
+);
+
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'error';
+ALTER TABLE expressions ADD COLUMN error_message text NOT NULL DEFAULT '';
+
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'timed_out';
+ALTER TABLE expressions ADD COLUMN deadline timestamp;
+
+CREATE TABLE IF NOT EXISTS expression_steps (
+    step_id int GENERATED ALWAYS AS IDENTITY,
+    expression_id int NOT NULL,
+    node_id int NOT NULL,
+    token text NOT NULL,
+    agent_id int,
+    result text NOT NULL DEFAULT '',
+    error_message text NOT NULL DEFAULT '',
+    dispatched_at timestamp NOT NULL,
+    computed_at timestamp,
+
+    PRIMARY KEY(step_id),
+    FOREIGN KEY(expression_id)
+      REFERENCES expressions(expression_id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS expression_steps_expression_id_idx ON expression_steps(expression_id, node_id);
+
+CREATE TABLE IF NOT EXISTS batches (
+    batch_id int GENERATED ALWAYS AS IDENTITY,
+    user_id int NOT NULL,
+    created_at timestamp NOT NULL,
+
+    PRIMARY KEY(batch_id),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE
+);
+
+ALTER TABLE expressions ADD COLUMN batch_id int REFERENCES batches(batch_id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expressions_batch_id_idx ON expressions(batch_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id int NOT NULL,
+    key text NOT NULL,
+    request_hash text NOT NULL,
+    expression_id int NOT NULL,
+    created_at timestamp NOT NULL,
+    expires_at timestamp NOT NULL,
+
+    PRIMARY KEY(user_id, key),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE,
+    FOREIGN KEY(expression_id)
+      REFERENCES expressions(expression_id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys(expires_at);
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS expressions_user_id_created_at_idx ON expressions(user_id, created_at, expression_id);
+CREATE INDEX IF NOT EXISTS expressions_user_id_updated_at_idx ON expressions(user_id, updated_at, expression_id);
+CREATE INDEX IF NOT EXISTS expressions_status_idx ON expressions(status);
+CREATE INDEX IF NOT EXISTS expressions_data_trgm_idx ON expressions USING gin (data gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash text NOT NULL,
+    family_id text NOT NULL,
+    user_id int NOT NULL,
+    created_at timestamp NOT NULL,
+    expires_at timestamp NOT NULL,
+    rotated_at timestamp,
+    revoked_at timestamp,
+
+    PRIMARY KEY(token_hash),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_expires_at_idx ON refresh_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti text NOT NULL,
+    expires_at timestamp NOT NULL,
+
+    PRIMARY KEY(jti)
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens(expires_at);
+
+ALTER TABLE users ADD COLUMN roles text[] NOT NULL DEFAULT '{user}';
+ALTER TABLE users ADD CONSTRAINT users_roles_check CHECK (roles <@ ARRAY['user', 'admin']::text[]);
+
+CREATE TABLE IF NOT EXISTS default_operations (
+    operation_type text NOT NULL,
+    execution_time int NOT NULL DEFAULT 100,
+
+    PRIMARY KEY(operation_type)
+);
+
+INSERT INTO default_operations (operation_type) VALUES
+('+'), ('-'), ('*'), ('/'), ('^'), ('%'), ('//'),
+('sqrt'), ('abs'), ('pow'), ('min'), ('max')
+ON CONFLICT (operation_type) DO NOTHING;
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS dead_letters (
+    dead_letter_id int GENERATED ALWAYS AS IDENTITY,
+    queue text NOT NULL,
+    body text NOT NULL,
+    reason text NOT NULL,
+    retries int NOT NULL,
+    created_at timestamp NOT NULL,
+
+    PRIMARY KEY(dead_letter_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS dead_letters;
+
+-- +goose Up
+-- Republished nodes kept a step per publish, only the last one was completed.
+DELETE FROM expression_steps
+WHERE step_id NOT IN (
+    SELECT max(step_id)
+    FROM expression_steps
+    GROUP BY expression_id, node_id
+);
+
+DROP INDEX IF EXISTS expression_steps_expression_id_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS expression_steps_expression_id_node_id_idx ON expression_steps(expression_id, node_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS expression_steps_expression_id_node_id_idx;
+CREATE INDEX IF NOT EXISTS expression_steps_expression_id_idx ON expression_steps(expression_id, node_id);
\ No newline at end of file
@@ -16,6 +16,14 @@ SELECT
 FROM agents
 ORDER BY created_at DESC;
 
+-- name: GetAgentByID :one
+SELECT
+    agent_id, number_of_parallel_calculations,
+    last_ping, status, created_at,
+    number_of_active_calculations
+FROM agents
+WHERE agent_id = $1;
+
 -- name: UpdateAgentLastPing :exec
 UPDATE agents
 SET last_ping = $1
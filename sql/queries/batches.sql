@@ -0,0 +1,21 @@
+-- name: CreateBatch :one
+INSERT INTO batches
+    (user_id, created_at)
+VALUES
+    ($1, $2)
+RETURNING
+    batch_id, user_id, created_at;
+
+-- name: GetBatchByID :one
+SELECT
+    batch_id, user_id, created_at
+FROM batches
+WHERE batch_id = $1 AND user_id = $2;
+
+-- name: GetBatchProgress :one
+SELECT
+    count(*) AS total,
+    count(*) FILTER (WHERE status = 'result') AS completed,
+    count(*) FILTER (WHERE status IN ('overflow', 'error', 'cancelled', 'timed_out')) AS failed
+FROM expressions
+WHERE batch_id = $1;
\ No newline at end of file
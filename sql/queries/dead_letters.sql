@@ -0,0 +1,19 @@
+-- name: CreateDeadLetter :one
+INSERT INTO dead_letters
+    (queue, body, reason, retries, created_at)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: DeleteDeadLetter :one
+DELETE FROM dead_letters
+WHERE dead_letter_id = $1
+RETURNING *;
+
+-- name: GetDeadLetterByID :one
+SELECT * FROM dead_letters
+WHERE dead_letter_id = $1;
+
+-- name: GetDeadLetters :many
+SELECT * FROM dead_letters
+ORDER BY dead_letter_id DESC;
\ No newline at end of file
@@ -0,0 +1,65 @@
+-- name: CreateExpressionNode :exec
+INSERT INTO expression_nodes
+    (expression_id, node_id, parent_id, operand_index, operator, value, status)
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetExpressionNodes :many
+SELECT
+    expression_id, node_id, parent_id,
+    operand_index, operator, value, status
+FROM expression_nodes
+WHERE expression_id = $1
+ORDER BY node_id;
+
+-- name: GetExpressionProgress :one
+SELECT
+    count(*) AS total,
+    count(*) FILTER (WHERE status = 'done') AS done
+FROM expression_nodes
+WHERE expression_id = $1 AND operator <> '';
+
+-- name: GetExpressionNodeByID :one
+SELECT
+    expression_id, node_id, parent_id,
+    operand_index, operator, value, status
+FROM expression_nodes
+WHERE expression_id = $1 AND node_id = $2;
+
+-- name: LockExpressionNode :exec
+SELECT node_id
+FROM expression_nodes
+WHERE expression_id = $1 AND node_id = $2
+FOR UPDATE;
+
+-- name: GetReadyExpressionNodes :many
+SELECT
+    expression_id, node_id, parent_id,
+    operand_index, operator, value, status
+FROM expression_nodes
+WHERE expression_id = $1 AND status = 'ready'
+ORDER BY node_id;
+
+-- name: GetExpressionNodeOperands :many
+SELECT value
+FROM expression_nodes
+WHERE expression_id = $1 AND parent_id = $2
+ORDER BY operand_index;
+
+-- name: MakeExpressionNodeDone :execrows
+UPDATE expression_nodes
+SET value = $1, status = 'done'
+WHERE expression_id = $2 AND node_id = $3 AND status = 'ready';
+
+-- name: MakeExpressionNodeReady :execrows
+UPDATE expression_nodes
+SET status = 'ready'
+WHERE expression_nodes.expression_id = $1 AND expression_nodes.node_id = $2
+    AND expression_nodes.status = 'waiting'
+    AND NOT EXISTS (
+        SELECT 1
+        FROM expression_nodes AS operands
+        WHERE operands.expression_id = $1
+            AND operands.parent_id = $2
+            AND operands.status != 'done'
+    );
\ No newline at end of file
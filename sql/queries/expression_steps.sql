@@ -0,0 +1,20 @@
+-- name: CompleteExpressionStep :exec
+UPDATE expression_steps
+SET agent_id = $1, result = $2, error_message = $3, computed_at = $4
+WHERE expression_id = $5 AND node_id = $6 AND computed_at IS NULL;
+
+-- name: GetExpressionSteps :many
+SELECT
+    step_id, expression_id, node_id, token, agent_id,
+    result, error_message, dispatched_at, computed_at
+FROM expression_steps
+WHERE expression_id = $1
+ORDER BY step_id;
+
+-- name: UpsertExpressionStep :exec
+INSERT INTO expression_steps
+    (expression_id, node_id, token, dispatched_at)
+VALUES
+    ($1, $2, $3, $4)
+ON CONFLICT (expression_id, node_id) DO UPDATE
+SET dispatched_at = EXCLUDED.dispatched_at;
\ No newline at end of file
@@ -1,27 +1,87 @@
 -- name: CreateExpression :one
 INSERT INTO expressions
-    (created_at, updated_at, data, parse_data, status, user_id)
+    (created_at, updated_at, data, parse_data, status, user_id, mode, bindings, deadline, batch_id)
 VALUES
-    ($1, $2, $3, $4, $5, $6)
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready;
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id;
 
 -- name: GetExpressions :many
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE user_id = $1
 ORDER BY created_at DESC;
 
+-- name: GetExpressionsByCreatedAtAsc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+    AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR status::text = ANY(sqlc.arg(statuses)::text[]))
+    AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+    AND (sqlc.arg(search)::text = '' OR data ILIKE '%' || sqlc.arg(search) || '%')
+    AND (sqlc.narg(cursor_time)::timestamp IS NULL OR (created_at, expression_id) > (sqlc.narg(cursor_time), sqlc.arg(cursor_id)::int))
+ORDER BY created_at ASC, expression_id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetExpressionsByCreatedAtDesc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+    AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR status::text = ANY(sqlc.arg(statuses)::text[]))
+    AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+    AND (sqlc.arg(search)::text = '' OR data ILIKE '%' || sqlc.arg(search) || '%')
+    AND (sqlc.narg(cursor_time)::timestamp IS NULL OR (created_at, expression_id) < (sqlc.narg(cursor_time), sqlc.arg(cursor_id)::int))
+ORDER BY created_at DESC, expression_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetExpressionsByUpdatedAtAsc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+    AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR status::text = ANY(sqlc.arg(statuses)::text[]))
+    AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+    AND (sqlc.arg(search)::text = '' OR data ILIKE '%' || sqlc.arg(search) || '%')
+    AND (sqlc.narg(cursor_time)::timestamp IS NULL OR (updated_at, expression_id) > (sqlc.narg(cursor_time), sqlc.arg(cursor_id)::int))
+ORDER BY updated_at ASC, expression_id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetExpressionsByUpdatedAtDesc :many
+SELECT
+    expression_id, user_id, agent_id,
+    created_at, updated_at, data, parse_data,
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
+FROM expressions
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+    AND (cardinality(sqlc.arg(statuses)::text[]) = 0 OR status::text = ANY(sqlc.arg(statuses)::text[]))
+    AND (sqlc.narg(created_from)::timestamp IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamp IS NULL OR created_at < sqlc.narg(created_to))
+    AND (sqlc.arg(search)::text = '' OR data ILIKE '%' || sqlc.arg(search) || '%')
+    AND (sqlc.narg(cursor_time)::timestamp IS NULL OR (updated_at, expression_id) < (sqlc.narg(cursor_time), sqlc.arg(cursor_id)::int))
+ORDER BY updated_at DESC, expression_id DESC
+LIMIT sqlc.arg(page_size);
+
 -- name: GetExpressionByID :one
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE expression_id = $1;
 
@@ -33,18 +93,39 @@ WHERE expression_id = $2;
 -- name: MakeExpressionReady :exec
 UPDATE expressions
 SET parse_data = $1, result = $2, updated_at = $3, is_ready = True, status = 'result'
-WHERE expression_id = $4;
+WHERE expression_id = $4 AND status IN ('ready_for_computation', 'computing', 'terminated');
+
+-- name: MakeExpressionOverflow :execrows
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'overflow'
+WHERE expression_id = $2 AND status IN ('ready_for_computation', 'computing', 'terminated');
+
+-- name: MakeExpressionError :exec
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'error', error_message = $2
+WHERE expression_id = $3 AND status IN ('ready_for_computation', 'computing', 'terminated');
+
+-- name: MakeExpressionCancelled :execrows
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'cancelled'
+WHERE expression_id = $2 AND user_id = $3 AND status IN ('ready_for_computation', 'computing', 'terminated');
+
+-- name: MakeExpressionsTimedOut :many
+UPDATE expressions
+SET parse_data = '', updated_at = $1, status = 'timed_out', error_message = 'deadline exceeded'
+WHERE deadline < $1 AND status IN ('ready_for_computation', 'computing', 'terminated')
+RETURNING expression_id;
 
 -- name: UpdateExpressionStatus :exec
 UPDATE expressions
 SET status = $1
-WHERE expression_id = $2;
+WHERE expression_id = $2 AND status IN ('ready_for_computation', 'computing', 'terminated');
 
 -- name: GetComputingExpressions :many
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE status IN ('ready_for_computation', 'computing', 'terminated')
 ORDER BY created_at DESC;
@@ -52,13 +133,13 @@ ORDER BY created_at DESC;
 -- name: MakeExpressionsTerminated :exec
 UPDATE expressions
 SET status = 'terminated'
-WHERE agent_id = $1 AND is_ready = false;
+WHERE agent_id = $1 AND status IN ('ready_for_computation', 'computing');
 
 -- name: GetTerminatedExpressions :many
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE status = 'terminated'
 ORDER BY created_at DESC;
@@ -72,7 +153,12 @@ WHERE expression_id = $2;
 SELECT
     expression_id, user_id, agent_id,
     created_at, updated_at, data, parse_data,
-    status, result, is_ready
+    status, result, is_ready, mode, bindings, error_message, deadline, batch_id
 FROM expressions
 WHERE status = 'computing'
-ORDER BY created_at DESC;
\ No newline at end of file
+ORDER BY created_at DESC;
+
+-- name: GetFinishedExpressionIDs :many
+SELECT expression_id
+FROM expressions
+WHERE expression_id = ANY($1::int[]) AND status IN ('result', 'overflow', 'error', 'cancelled', 'timed_out');
\ No newline at end of file
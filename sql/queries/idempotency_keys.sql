@@ -0,0 +1,21 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys
+    (user_id, key, request_hash, expression_id, created_at, expires_at)
+VALUES
+    ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash, expression_id = EXCLUDED.expression_id,
+    created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+RETURNING
+    user_id, key, request_hash, expression_id, created_at, expires_at;
+
+-- name: GetIdempotencyKey :one
+SELECT
+    user_id, key, request_hash, expression_id, created_at, expires_at
+FROM idempotency_keys
+WHERE user_id = $1 AND key = $2 AND expires_at > $3;
+
+-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE expires_at <= $1;
\ No newline at end of file
@@ -17,8 +17,25 @@ FROM operations
 WHERE operation_type = $1 AND user_id = $2;
 
 -- name: NewOperationsForUser :exec
-INSERT INTO operations (operation_type, user_id) VALUES 
-('+', $1),
-('-', $1),
-('*', $1),
-('/', $1);
\ No newline at end of file
+INSERT INTO operations
+    (operation_type, execution_time, user_id)
+SELECT
+    operation_type, execution_time, $1
+FROM default_operations;
+
+-- name: GetDefaultOperations :many
+SELECT
+    operation_type, execution_time
+FROM default_operations
+ORDER BY operation_type DESC;
+
+-- name: UpdateDefaultOperationTime :one
+UPDATE default_operations
+SET execution_time = $1
+WHERE operation_type = $2
+RETURNING operation_type, execution_time;
+
+-- name: UpdateOperationTimeForAllUsers :exec
+UPDATE operations
+SET execution_time = $1
+WHERE operation_type = $2;
\ No newline at end of file
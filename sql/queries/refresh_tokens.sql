@@ -0,0 +1,37 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens
+    (token_hash, family_id, user_id, created_at, expires_at)
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING
+    token_hash, family_id, user_id, created_at, expires_at, rotated_at, revoked_at;
+
+-- name: GetRefreshTokenForUpdate :one
+SELECT
+    token_hash, family_id, user_id, created_at, expires_at, rotated_at, revoked_at
+FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: MakeRefreshTokenRotated :exec
+UPDATE refresh_tokens
+SET rotated_at = $1
+WHERE token_hash = $2;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = $1
+WHERE family_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE family_id = (
+    SELECT family_id
+    FROM refresh_tokens AS rt
+    WHERE rt.token_hash = sqlc.arg(token_hash) AND rt.user_id = sqlc.arg(user_id)
+) AND revoked_at IS NULL;
+
+-- name: DeleteExpiredRefreshTokens :exec
+DELETE FROM refresh_tokens
+WHERE expires_at <= $1;
\ No newline at end of file
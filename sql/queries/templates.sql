@@ -0,0 +1,24 @@
+-- name: CreateTemplate :one
+INSERT INTO templates
+    (user_id, name, data, parse_data, variables, created_at)
+VALUES
+    ($1, $2, $3, $4, $5, $6)
+RETURNING
+    template_id, user_id, name, data, parse_data, variables, created_at;
+
+-- name: GetTemplates :many
+SELECT
+    template_id, user_id, name, data, parse_data, variables, created_at
+FROM templates
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetTemplateByID :one
+SELECT
+    template_id, user_id, name, data, parse_data, variables, created_at
+FROM templates
+WHERE template_id = $1 AND user_id = $2;
+
+-- name: DeleteTemplate :execrows
+DELETE FROM templates
+WHERE template_id = $1 AND user_id = $2;
\ No newline at end of file
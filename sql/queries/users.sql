@@ -1,11 +1,27 @@
 -- name: GetUser :one
-SELECT user_id, email, password_hash
+SELECT user_id, email, password_hash, roles
 FROM users
 WHERE email = $1;
 
 -- name: SaveUser :one
 INSERT INTO users
-    (email, password_hash)
+    (email, password_hash, roles)
 VALUES
-    ($1, $2)
-RETURNING user_id;
\ No newline at end of file
+    ($1, $2, $3)
+RETURNING user_id;
+
+-- name: GetUserByID :one
+SELECT user_id, email, password_hash, roles
+FROM users
+WHERE user_id = $1;
+
+-- name: GetUsers :many
+SELECT user_id, email, password_hash, roles
+FROM users
+ORDER BY user_id;
+
+-- name: UpdateUserRoles :one
+UPDATE users
+SET roles = $1
+WHERE user_id = $2
+RETURNING user_id, email, password_hash, roles;
\ No newline at end of file
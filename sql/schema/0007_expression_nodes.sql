@@ -0,0 +1,22 @@
+-- +goose Up
+DROP TYPE IF EXISTS node_status;
+CREATE TYPE node_status AS ENUM ('waiting', 'ready', 'done');
+
+CREATE TABLE IF NOT EXISTS expression_nodes (
+    expression_id int NOT NULL,
+    node_id int NOT NULL,
+    parent_id int,
+    operand_index int NOT NULL DEFAULT 0,
+    operator text NOT NULL DEFAULT '',
+    value text NOT NULL DEFAULT '',
+    status node_status NOT NULL,
+
+    PRIMARY KEY(expression_id, node_id),
+    FOREIGN KEY(expression_id)
+      REFERENCES expressions(expression_id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS expression_nodes;
+DROP TYPE IF EXISTS node_status;
\ No newline at end of file
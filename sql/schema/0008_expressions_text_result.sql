@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE expressions ALTER COLUMN result TYPE text;
+ALTER TABLE expressions ALTER COLUMN result SET DEFAULT '0';
+
+-- +goose Down
+ALTER TABLE expressions ALTER COLUMN result SET DEFAULT 0;
+ALTER TABLE expressions ALTER COLUMN result TYPE int USING round(result::numeric);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+DROP TYPE IF EXISTS evaluation_mode;
+CREATE TYPE evaluation_mode AS ENUM ('standard', 'arbitrary');
+
+ALTER TABLE expressions ADD COLUMN mode evaluation_mode NOT NULL DEFAULT 'standard';
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'overflow';
+
+-- +goose Down
+ALTER TABLE expressions DROP COLUMN mode;
+DROP TYPE IF EXISTS evaluation_mode;
\ No newline at end of file
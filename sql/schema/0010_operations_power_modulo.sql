@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE operations ALTER COLUMN operation_type TYPE text;
+
+INSERT INTO operations (operation_type, user_id)
+SELECT new_operations.operation_type, users.user_id
+FROM users
+CROSS JOIN (VALUES ('^'), ('%'), ('//')) AS new_operations(operation_type)
+ON CONFLICT (operation_type, user_id) DO NOTHING;
+
+-- +goose Down
+DELETE FROM operations WHERE operation_type IN ('^', '%', '//');
+ALTER TABLE operations ALTER COLUMN operation_type TYPE varchar(1);
\ No newline at end of file
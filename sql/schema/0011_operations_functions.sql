@@ -0,0 +1,9 @@
+-- +goose Up
+INSERT INTO operations (operation_type, user_id)
+SELECT functions.operation_type, users.user_id
+FROM users
+CROSS JOIN (VALUES ('sqrt'), ('abs'), ('pow'), ('min'), ('max')) AS functions(operation_type)
+ON CONFLICT (operation_type, user_id) DO NOTHING;
+
+-- +goose Down
+DELETE FROM operations WHERE operation_type IN ('sqrt', 'abs', 'pow', 'min', 'max');
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE expressions ADD COLUMN bindings jsonb NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS templates (
+    template_id int GENERATED ALWAYS AS IDENTITY,
+    user_id int NOT NULL,
+    name text NOT NULL,
+    data text NOT NULL,
+    parse_data text NOT NULL,
+    variables text[] NOT NULL DEFAULT '{}',
+    created_at timestamp NOT NULL,
+
+    PRIMARY KEY(template_id),
+    CONSTRAINT template_name_user_id UNIQUE(name, user_id),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS templates;
+ALTER TABLE expressions DROP COLUMN bindings;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'error';
+ALTER TABLE expressions ADD COLUMN error_message text NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE expressions DROP COLUMN error_message;
\ No newline at end of file
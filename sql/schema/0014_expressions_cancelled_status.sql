@@ -0,0 +1,5 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+-- +goose Down
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE expression_status ADD VALUE IF NOT EXISTS 'timed_out';
+ALTER TABLE expressions ADD COLUMN deadline timestamp;
+
+-- +goose Down
+ALTER TABLE expressions DROP COLUMN deadline;
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS expression_steps (
+    step_id int GENERATED ALWAYS AS IDENTITY,
+    expression_id int NOT NULL,
+    node_id int NOT NULL,
+    token text NOT NULL,
+    agent_id int,
+    result text NOT NULL DEFAULT '',
+    error_message text NOT NULL DEFAULT '',
+    dispatched_at timestamp NOT NULL,
+    computed_at timestamp,
+
+    PRIMARY KEY(step_id),
+    FOREIGN KEY(expression_id)
+      REFERENCES expressions(expression_id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS expression_steps_expression_id_idx ON expression_steps(expression_id, node_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS expression_steps;
\ No newline at end of file
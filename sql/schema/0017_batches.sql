@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS batches (
+    batch_id int GENERATED ALWAYS AS IDENTITY,
+    user_id int NOT NULL,
+    created_at timestamp NOT NULL,
+
+    PRIMARY KEY(batch_id),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE
+);
+
+ALTER TABLE expressions ADD COLUMN batch_id int REFERENCES batches(batch_id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expressions_batch_id_idx ON expressions(batch_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS expressions_batch_id_idx;
+ALTER TABLE expressions DROP COLUMN batch_id;
+DROP TABLE IF EXISTS batches;
\ No newline at end of file
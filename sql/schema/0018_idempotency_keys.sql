@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id int NOT NULL,
+    key text NOT NULL,
+    request_hash text NOT NULL,
+    expression_id int NOT NULL,
+    created_at timestamp NOT NULL,
+    expires_at timestamp NOT NULL,
+
+    PRIMARY KEY(user_id, key),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE,
+    FOREIGN KEY(expression_id)
+      REFERENCES expressions(expression_id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_keys;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS expressions_user_id_created_at_idx ON expressions(user_id, created_at, expression_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS expressions_user_id_updated_at_idx ON expressions(user_id, updated_at, expression_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS expressions_status_idx ON expressions(status);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS expressions_data_trgm_idx ON expressions USING gin (data gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS expressions_data_trgm_idx;
+DROP INDEX CONCURRENTLY IF EXISTS expressions_status_idx;
+DROP INDEX CONCURRENTLY IF EXISTS expressions_user_id_updated_at_idx;
+DROP INDEX CONCURRENTLY IF EXISTS expressions_user_id_created_at_idx;
\ No newline at end of file
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash text NOT NULL,
+    family_id text NOT NULL,
+    user_id int NOT NULL,
+    created_at timestamp NOT NULL,
+    expires_at timestamp NOT NULL,
+    rotated_at timestamp,
+    revoked_at timestamp,
+
+    PRIMARY KEY(token_hash),
+    FOREIGN KEY(user_id)
+      REFERENCES users(user_id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_expires_at_idx ON refresh_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti text NOT NULL,
+    expires_at timestamp NOT NULL,
+
+    PRIMARY KEY(jti)
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN roles text[] NOT NULL DEFAULT '{user}';
+ALTER TABLE users ADD CONSTRAINT users_roles_check CHECK (roles <@ ARRAY['user', 'admin']::text[]);
+
+CREATE TABLE IF NOT EXISTS default_operations (
+    operation_type text NOT NULL,
+    execution_time int NOT NULL DEFAULT 100,
+
+    PRIMARY KEY(operation_type)
+);
+
+INSERT INTO default_operations (operation_type) VALUES
+('+'), ('-'), ('*'), ('/'), ('^'), ('%'), ('//'),
+('sqrt'), ('abs'), ('pow'), ('min'), ('max')
+ON CONFLICT (operation_type) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS default_operations;
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS dead_letters (
+    dead_letter_id int GENERATED ALWAYS AS IDENTITY,
+    queue text NOT NULL,
+    body text NOT NULL,
+    reason text NOT NULL,
+    retries int NOT NULL,
+    created_at timestamp NOT NULL,
+
+    PRIMARY KEY(dead_letter_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS dead_letters;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Republished nodes kept a step per publish, only the last one was completed.
+DELETE FROM expression_steps
+WHERE step_id NOT IN (
+    SELECT max(step_id)
+    FROM expression_steps
+    GROUP BY expression_id, node_id
+);
+
+DROP INDEX IF EXISTS expression_steps_expression_id_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS expression_steps_expression_id_node_id_idx ON expression_steps(expression_id, node_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS expression_steps_expression_id_node_id_idx;
+CREATE INDEX IF NOT EXISTS expression_steps_expression_id_idx ON expression_steps(expression_id, node_id);
\ No newline at end of file